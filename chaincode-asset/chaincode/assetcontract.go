@@ -0,0 +1,292 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-asset/cbdcerr"
+)
+
+const (
+	holdingType = "holding"
+	earmarkType = "earmark"
+
+	// issuerMSP is the organization that registers issued assets.
+	issuerMSP = "centralbankOrg"
+
+	// ownerAttr is the Fabric CA enrollment attribute naming the bank a client operates.
+	ownerAttr = "bankID"
+
+	// settlementChaincode settles delivery-versus-payment against CBDC on this channel.
+	// Earmarks are only made and closed through it, so that an asset is delivered only
+	// in the transaction that pays for it.
+	settlementChaincode = "regulatorychaincode"
+
+	earmarkOpen      = "open"
+	earmarkReleased  = "released"
+	earmarkCancelled = "cancelled"
+)
+
+// AssetContract is a reference ledger of tokenized securities, such as bonds,
+// held in units by banks. It is used to settle delivery-versus-payment against CBDC
+// in the regulatory chaincode on the same channel.
+type AssetContract struct {
+	contractapi.Contract
+}
+
+// Holding is the quantity of an asset a bank owns.
+type Holding struct {
+	AssetID  string `json:"assetID"`
+	Owner    string `json:"owner"`
+	Quantity int    `json:"quantity"`
+}
+
+// Earmark is a quantity set aside from the owner's holding for a beneficiary by a DvP
+// instruction. Settling the DvP releases it to the beneficiary, cancelling it returns it to the owner.
+type Earmark struct {
+	ID          string `json:"ID"`
+	AssetID     string `json:"assetID"`
+	Owner       string `json:"owner"`
+	Beneficiary string `json:"beneficiary"`
+	Quantity    int    `json:"quantity"`
+	Status      string `json:"status"`
+}
+
+// isOwner reports whether the client is enrolled as an operator of bank id.
+func isOwner(ctx contractapi.TransactionContextInterface, id string) bool {
+	owner, found, err := ctx.GetClientIdentity().GetAttributeValue(ownerAttr)
+	return err == nil && found && owner == id
+}
+
+// invokedChaincode returns the name of the chaincode the client invoked. It is not this
+// chaincode when this one is called by another chaincode on the same channel.
+func invokedChaincode(ctx contractapi.TransactionContextInterface) (string, error) {
+	signedProposal, err := ctx.GetStub().GetSignedProposal()
+	if err != nil {
+		return "", err
+	}
+	if signedProposal == nil {
+		return "", cbdcerr.New(cbdcerr.Unauthorized, "the transaction has no proposal")
+	}
+	var proposal peer.Proposal
+	err = proto.Unmarshal(signedProposal.ProposalBytes, &proposal)
+	if err != nil {
+		return "", err
+	}
+	var payload peer.ChaincodeProposalPayload
+	err = proto.Unmarshal(proposal.Payload, &payload)
+	if err != nil {
+		return "", err
+	}
+	var spec peer.ChaincodeInvocationSpec
+	err = proto.Unmarshal(payload.Input, &spec)
+	if err != nil {
+		return "", err
+	}
+	return spec.GetChaincodeSpec().GetChaincodeId().GetName(), nil
+}
+
+// checkSettlement fails unless the client invoked the settlement chaincode.
+func checkSettlement(ctx contractapi.TransactionContextInterface, earmarkID string) error {
+	name, err := invokedChaincode(ctx)
+	if err != nil {
+		return err
+	}
+	if name != settlementChaincode {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the earmark %s can only be changed by DvP settlement in %s", earmarkID, settlementChaincode)
+	}
+	return nil
+}
+
+// IssueAsset registers quantity of an asset to its first owner. Only the issuer can issue.
+func (s *AssetContract) IssueAsset(ctx contractapi.TransactionContextInterface, assetID string, owner string, quantity int) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+	if mspID != issuerMSP {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the issuer can issue %s", assetID)
+	}
+	if quantity <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "quantity must be positive")
+	}
+	holding, err := s.ReadHolding(ctx, assetID, owner)
+	if err != nil {
+		return err
+	}
+	holding.Quantity = holding.Quantity + quantity
+	return s.putHolding(ctx, holding)
+}
+
+// ReadHolding returns the quantity of an asset owned by owner.
+func (s *AssetContract) ReadHolding(ctx contractapi.TransactionContextInterface, assetID string, owner string) (*Holding, error) {
+	holding := Holding{AssetID: assetID, Owner: owner}
+	_, err := s.getRecord(ctx, holdingType, []string{assetID, owner}, &holding)
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// TransferAsset moves quantity of an asset from its owner to another bank.
+func (s *AssetContract) TransferAsset(ctx contractapi.TransactionContextInterface, assetID string, from string, to string, quantity int) error {
+	if !isOwner(ctx, from) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can transfer its holding of %s", from, assetID)
+	}
+	err := s.debit(ctx, assetID, from, quantity)
+	if err != nil {
+		return err
+	}
+	return s.credit(ctx, assetID, to, quantity)
+}
+
+// EarmarkAsset sets quantity of the owner's holding aside for beneficiary under earmarkID.
+// It is called by InstructDvP of the settlement chaincode.
+func (s *AssetContract) EarmarkAsset(ctx contractapi.TransactionContextInterface, earmarkID string, assetID string, owner string, beneficiary string, quantity int) error {
+	if !isOwner(ctx, owner) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can earmark its holding of %s", owner, assetID)
+	}
+	if owner == beneficiary {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "%s cannot earmark its holding for itself", owner)
+	}
+	err := checkSettlement(ctx, earmarkID)
+	if err != nil {
+		return err
+	}
+	found, err := s.getRecord(ctx, earmarkType, []string{earmarkID}, &Earmark{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the earmark %s already exists", earmarkID)
+	}
+	err = s.debit(ctx, assetID, owner, quantity)
+	if err != nil {
+		return err
+	}
+	earmark := Earmark{
+		ID:          earmarkID,
+		AssetID:     assetID,
+		Owner:       owner,
+		Beneficiary: beneficiary,
+		Quantity:    quantity,
+		Status:      earmarkOpen,
+	}
+	return s.putRecord(ctx, earmarkType, []string{earmarkID}, &earmark)
+}
+
+// ReleaseEarmark delivers an earmarked quantity to its beneficiary. It is called by
+// SettleDvP of the settlement chaincode, in the transaction that pays for the asset.
+func (s *AssetContract) ReleaseEarmark(ctx contractapi.TransactionContextInterface, earmarkID string) error {
+	earmark, err := s.ReadEarmark(ctx, earmarkID)
+	if err != nil {
+		return err
+	}
+	if !isOwner(ctx, earmark.Beneficiary) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can release the earmark %s", earmark.Beneficiary, earmarkID)
+	}
+	err = checkSettlement(ctx, earmarkID)
+	if err != nil {
+		return err
+	}
+	return s.closeEarmark(ctx, earmark, earmark.Beneficiary, earmarkReleased)
+}
+
+// CancelEarmark returns an earmarked quantity to its owner. It is called by CancelDvP
+// of the settlement chaincode.
+func (s *AssetContract) CancelEarmark(ctx contractapi.TransactionContextInterface, earmarkID string) error {
+	earmark, err := s.ReadEarmark(ctx, earmarkID)
+	if err != nil {
+		return err
+	}
+	if !isOwner(ctx, earmark.Owner) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can cancel the earmark %s", earmark.Owner, earmarkID)
+	}
+	err = checkSettlement(ctx, earmarkID)
+	if err != nil {
+		return err
+	}
+	return s.closeEarmark(ctx, earmark, earmark.Owner, earmarkCancelled)
+}
+
+func (s *AssetContract) ReadEarmark(ctx contractapi.TransactionContextInterface, earmarkID string) (*Earmark, error) {
+	var earmark Earmark
+	found, err := s.getRecord(ctx, earmarkType, []string{earmarkID}, &earmark)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the earmark %s does not exist", earmarkID)
+	}
+	return &earmark, nil
+}
+
+func (s *AssetContract) closeEarmark(ctx contractapi.TransactionContextInterface, earmark *Earmark, to string, status string) error {
+	if earmark.Status != earmarkOpen {
+		return cbdcerr.New(cbdcerr.Rejected, "the earmark %s is already %s", earmark.ID, earmark.Status)
+	}
+	err := s.credit(ctx, earmark.AssetID, to, earmark.Quantity)
+	if err != nil {
+		return err
+	}
+	earmark.Status = status
+	return s.putRecord(ctx, earmarkType, []string{earmark.ID}, earmark)
+}
+
+func (s *AssetContract) debit(ctx contractapi.TransactionContextInterface, assetID string, owner string, quantity int) error {
+	if quantity <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "quantity must be positive")
+	}
+	holding, err := s.ReadHolding(ctx, assetID, owner)
+	if err != nil {
+		return err
+	}
+	if holding.Quantity < quantity {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "%s holds only %d of %s", owner, holding.Quantity, assetID)
+	}
+	holding.Quantity = holding.Quantity - quantity
+	return s.putHolding(ctx, holding)
+}
+
+func (s *AssetContract) credit(ctx contractapi.TransactionContextInterface, assetID string, owner string, quantity int) error {
+	holding, err := s.ReadHolding(ctx, assetID, owner)
+	if err != nil {
+		return err
+	}
+	holding.Quantity = holding.Quantity + quantity
+	return s.putHolding(ctx, holding)
+}
+
+func (s *AssetContract) putHolding(ctx contractapi.TransactionContextInterface, holding *Holding) error {
+	return s.putRecord(ctx, holdingType, []string{holding.AssetID, holding.Owner}, holding)
+}
+
+func (s *AssetContract) getRecord(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, v interface{}) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return false, err
+	}
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if recordJSON == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(recordJSON, v)
+}
+
+func (s *AssetContract) putRecord(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, v interface{}) error {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, recordJSON)
+}
@@ -0,0 +1,87 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-asset/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-asset/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// newEarmarked issues bond1 to Bank1 and earmarks 10 of it for Bank2 as dvp1.
+func newEarmarked(t *testing.T) *ledger {
+	s := chaincode.AssetContract{}
+	l := newLedger(t)
+	require.NoError(t, l.tx(issuer, "assetchaincode", func(ctx contractapi.TransactionContextInterface) error {
+		return s.IssueAsset(ctx, "bond1", "Bank1", 100)
+	}))
+	require.NoError(t, l.tx(seller, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error {
+		return s.EarmarkAsset(ctx, "dvp1", "bond1", "Bank1", "Bank2", 10)
+	}))
+	require.Equal(t, 90, l.holding("bond1", "Bank1"))
+	return l
+}
+
+func TestEarmarkRelease(t *testing.T) {
+	s := chaincode.AssetContract{}
+	l := newEarmarked(t)
+
+	err := l.tx(buyer, "assetchaincode", func(ctx contractapi.TransactionContextInterface) error { return s.ReleaseEarmark(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "the earmark dvp1 can only be changed by DvP settlement in regulatorychaincode")
+	err = l.tx(seller, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error { return s.ReleaseEarmark(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "only Bank2 can release the earmark dvp1")
+	require.Equal(t, 0, l.holding("bond1", "Bank2"))
+
+	require.NoError(t, l.tx(buyer, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error { return s.ReleaseEarmark(ctx, "dvp1") }))
+	require.Equal(t, 90, l.holding("bond1", "Bank1"))
+	require.Equal(t, 10, l.holding("bond1", "Bank2"))
+
+	err = l.tx(seller, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error { return s.CancelEarmark(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Rejected, "the earmark dvp1 is already released")
+}
+
+func TestEarmarkCancel(t *testing.T) {
+	s := chaincode.AssetContract{}
+	l := newEarmarked(t)
+
+	err := l.tx(seller, "assetchaincode", func(ctx contractapi.TransactionContextInterface) error { return s.CancelEarmark(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "the earmark dvp1 can only be changed by DvP settlement in regulatorychaincode")
+	require.Equal(t, 90, l.holding("bond1", "Bank1"))
+
+	require.NoError(t, l.tx(seller, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error { return s.CancelEarmark(ctx, "dvp1") }))
+	require.Equal(t, 100, l.holding("bond1", "Bank1"))
+
+	err = l.tx(buyer, "regulatorychaincode", func(ctx contractapi.TransactionContextInterface) error { return s.ReleaseEarmark(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Rejected, "the earmark dvp1 is already cancelled")
+	require.Equal(t, 0, l.holding("bond1", "Bank2"))
+}
+
+func TestEarmarkRejects(t *testing.T) {
+	s := chaincode.AssetContract{}
+	for _, tc := range []struct {
+		name        string
+		client      client
+		invoked     string
+		earmarkID   string
+		beneficiary string
+		quantity    int
+		code        cbdcerr.Code
+		err         string
+	}{
+		{"outside settlement", seller, "assetchaincode", "dvp2", "Bank2", 10, cbdcerr.Unauthorized, "the earmark dvp2 can only be changed by DvP settlement in regulatorychaincode"},
+		{"not the owner", buyer, "regulatorychaincode", "dvp2", "Bank2", 10, cbdcerr.Unauthorized, "only Bank1 can earmark its holding of bond1"},
+		{"for the owner", seller, "regulatorychaincode", "dvp2", "Bank1", 10, cbdcerr.InvalidArgument, "Bank1 cannot earmark its holding for itself"},
+		{"more than held", seller, "regulatorychaincode", "dvp2", "Bank2", 91, cbdcerr.InsufficientFunds, "Bank1 holds only 90 of bond1"},
+		{"earmark exists", seller, "regulatorychaincode", "dvp1", "Bank2", 10, cbdcerr.AlreadyExists, "the earmark dvp1 already exists"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newEarmarked(t)
+			err := l.tx(tc.client, tc.invoked, func(ctx contractapi.TransactionContextInterface) error {
+				return s.EarmarkAsset(ctx, tc.earmarkID, "bond1", "Bank1", tc.beneficiary, tc.quantity)
+			})
+			requireCode(t, err, tc.code, tc.err)
+			require.Equal(t, 90, l.holding("bond1", "Bank1"))
+		})
+	}
+}
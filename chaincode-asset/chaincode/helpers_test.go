@@ -0,0 +1,123 @@
+package chaincode_test
+
+import (
+	"container/list"
+	"crypto/x509"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-asset/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-asset/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// client is a client of mspID enrolled as an operator of bank.
+type client struct {
+	mspID string
+	bank  string
+}
+
+func (c client) GetID() (string, error)    { return c.bank, nil }
+func (c client) GetMSPID() (string, error) { return c.mspID, nil }
+func (c client) GetAttributeValue(name string) (string, bool, error) {
+	return c.bank, name == "bankID" && c.bank != "", nil
+}
+func (c client) AssertAttributeValue(name string, value string) error {
+	if name != "bankID" || value != c.bank {
+		return fmt.Errorf("attribute %s is not %s", name, value)
+	}
+	return nil
+}
+func (c client) GetX509Certificate() (*x509.Certificate, error) { return nil, nil }
+
+// proposalStub is a stub for a transaction proposed to the chaincode invoked.
+type proposalStub struct {
+	*shimtest.MockStub
+	invoked string
+}
+
+func (s proposalStub) GetSignedProposal() (*peer.SignedProposal, error) {
+	input, err := proto.Marshal(&peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{ChaincodeId: &peer.ChaincodeID{Name: s.invoked}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: input})
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := proto.Marshal(&peer.Proposal{Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return &peer.SignedProposal{ProposalBytes: proposal}, nil
+}
+
+// ledger is the world state of assetchaincode, changed one transaction at a time.
+type ledger struct {
+	t    *testing.T
+	stub *shimtest.MockStub
+	n    int
+}
+
+func newLedger(t *testing.T) *ledger {
+	return &ledger{t: t, stub: shimtest.NewMockStub("assetchaincode", nil)}
+}
+
+// tx runs fn as a transaction of c proposed to the chaincode invoked. Like a peer, it
+// discards the writes of a transaction that fails.
+func (l *ledger) tx(c client, invoked string, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	l.n++
+	txID := fmt.Sprintf("%s/tx%03d", l.t.Name(), l.n)
+	l.stub.MockTransactionStart(txID)
+	defer l.stub.MockTransactionEnd(txID)
+
+	state := make(map[string][]byte, len(l.stub.State))
+	for key, value := range l.stub.State {
+		state[key] = value
+	}
+	keys := list.New()
+	for e := l.stub.Keys.Front(); e != nil; e = e.Next() {
+		keys.PushBack(e.Value)
+	}
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(proposalStub{l.stub, invoked})
+	ctx.SetClientIdentity(c)
+	err := fn(ctx)
+	if err != nil {
+		l.stub.State, l.stub.Keys = state, keys
+	}
+	return err
+}
+
+func (l *ledger) holding(assetID string, owner string) int {
+	s := chaincode.AssetContract{}
+	var quantity int
+	require.NoError(l.t, l.tx(client{}, "assetchaincode", func(ctx contractapi.TransactionContextInterface) error {
+		holding, err := s.ReadHolding(ctx, assetID, owner)
+		if err != nil {
+			return err
+		}
+		quantity = holding.Quantity
+		return nil
+	}))
+	return quantity
+}
+
+var (
+	issuer = client{mspID: "centralbankOrg"}
+	seller = client{mspID: "commercialbankOrg", bank: "Bank1"}
+	buyer  = client{mspID: "commercialbankOrg", bank: "Bank2"}
+)
+
+// requireCode checks that err is the cbdcerr error of code with message.
+func requireCode(t *testing.T, err error, code cbdcerr.Code, message string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, &cbdcerr.Error{Code: code, Message: message}, cbdcerr.From(err))
+}
@@ -7,7 +7,9 @@ package main
 import (
 	"log"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
 )
 
@@ -26,7 +28,7 @@ func main() {
 		log.Panicf("Error creating asset-transfer-basic chaincode: %v", errT)
 	}
 
-	if errT := adminChaincode.Start(); errT != nil {
+	if errT := shim.Start(cbdcerr.Structured(chaincode.Idempotent(adminChaincode))); errT != nil {
 		log.Panicf("Error starting asset-transfer-basic chaincode: %v", errT)
 	}
 }
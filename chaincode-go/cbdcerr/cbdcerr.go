@@ -0,0 +1,117 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cbdcerr defines the error codes returned by the CBDC chaincodes.
+// An error is sent to clients as a JSON object such as
+//
+//	{"code":"INSUFFICIENT_FUNDS","message":"Lack of balance user1's Account"}
+//
+// in the message of the chaincode response, so clients and calling chaincodes
+// can branch on the code instead of the text.
+package cbdcerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Code is a stable error code. Codes are never renamed once released.
+type Code string
+
+const (
+	InsufficientFunds Code = "INSUFFICIENT_FUNDS"
+	LimitExceeded     Code = "LIMIT_EXCEEDED"
+	Unauthorized      Code = "UNAUTHORIZED"
+	NotFound          Code = "NOT_FOUND"
+	AlreadyExists     Code = "ALREADY_EXISTS"
+	InvalidArgument   Code = "INVALID_ARGUMENT"
+	Blocked           Code = "BLOCKED"
+	DoubleSpend       Code = "DOUBLE_SPEND"
+
+	// Rejected is the code of errors that were not given one.
+	Rejected Code = "REJECTED"
+)
+
+// Error is an error with a code.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the JSON encoding of e.
+func (e *Error) Error() string {
+	errJSON, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(errJSON)
+}
+
+// New returns an error with code and a message formatted as with fmt.Sprintf.
+func New(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// CodeOf returns the code of err, or Rejected if it has none.
+func CodeOf(err error) Code {
+	return From(err).Code
+}
+
+// From returns err as an *Error. Errors without a code are given Rejected.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Parse(err.Error())
+}
+
+// Parse decodes an error message returned by a chaincode.
+// A message that is not an encoded Error is given Rejected.
+func Parse(message string) *Error {
+	var e Error
+	if json.Unmarshal([]byte(message), &e) == nil && e.Code != "" {
+		return &e
+	}
+	return &Error{Code: Rejected, Message: message}
+}
+
+// FromResponse returns the error of a failed InvokeChaincode response with
+// the code the called chaincode gave it, or nil if the call succeeded.
+func FromResponse(response peer.Response) error {
+	if response.Status == shim.OK {
+		return nil
+	}
+	return Parse(response.Message)
+}
+
+type structuredChaincode struct {
+	cc shim.Chaincode
+}
+
+// Structured wraps a chaincode so that every error response carries an encoded Error,
+// giving Rejected to errors returned without a code.
+func Structured(cc shim.Chaincode) shim.Chaincode {
+	return &structuredChaincode{cc: cc}
+}
+
+func (s *structuredChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return structure(s.cc.Init(stub))
+}
+
+func (s *structuredChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	return structure(s.cc.Invoke(stub))
+}
+
+func structure(response peer.Response) peer.Response {
+	if response.Status < shim.ERRORTHRESHOLD {
+		return response
+	}
+	response.Message = Parse(response.Message).Error()
+	return response
+}
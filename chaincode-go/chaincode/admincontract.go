@@ -6,6 +6,7 @@ import (
 	"time"
 	"strconv"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
 
 )
 
@@ -25,6 +26,7 @@ type issueHistory struct {
 	BankID         string `json:"bankID"`
 	Price          string `json:"price"`
 	Date           string `json:"date"`
+	Currency       string `json:"currency,omitempty"`
 }
 
 const (
@@ -66,7 +68,7 @@ func (s *AdminContract) ReadTotalBalance(ctx contractapi.TransactionContextInter
 	}
 
 	if totalBalanceJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the asset %s does not exist", id)
 	}
 
 	var totalBalance totalBalance
@@ -95,7 +97,7 @@ func (s *AdminContract) UpdateTotalBalance(ctx contractapi.TransactionContextInt
 
 	newTBal := bal.TBalance + newBalance
 	if newTBal > MAX_VAL {
-		return fmt.Errorf("MAX VAL")
+		return cbdcerr.New(cbdcerr.LimitExceeded, "the total balance cannot exceed %d", MAX_VAL)
 	}
 
 	bal.Balance = newBal
@@ -170,11 +172,11 @@ func (s *AdminContract) TransferBalance(ctx contractapi.TransactionContextInterf
 	newBal := bal.Balance - priceNum
 
 	if newBal < 0 {
-		return fmt.Errorf("Lack of Balance")
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
 	}
 
 	if bankID != "Bank0" {
-		return fmt.Errorf("Only the head office of a bank can issue a CBDC from the central bank!!")
+		return cbdcerr.New(cbdcerr.Unauthorized, "Only the head office of a bank can issue a CBDC from the central bank!!")
 	}
 
 	bal.Balance = newBal
@@ -188,7 +190,7 @@ func (s *AdminContract) TransferBalance(ctx contractapi.TransactionContextInterf
 
 	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
 	if response.Status != 200 {
-		return fmt.Errorf("Failed to query chaincode. Got Error: %s", response.Payload)
+		return cbdcerr.FromResponse(response)
 	}
 	error := ctx.GetStub().PutState(bankID, []byte(response.Payload))
 	if error != nil {
@@ -261,7 +263,7 @@ func (s *AdminContract) ReadTransferTest(ctx contractapi.TransactionContextInter
 
 	response := ctx.GetStub().InvokeChaincode("userchaincode", queryArgs, "user-channel")
 	if response.Status != 200 {
-		return "", fmt.Errorf("Failed to query chaincode. Got Error: %s", response.Payload)
+		return "", cbdcerr.FromResponse(response)
 	}
 	return string(response.Payload), nil
 }
\ No newline at end of file
@@ -0,0 +1,344 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	collateralType = "collateral"
+	creditLineType = "creditLine"
+	appliedType    = "applied"
+
+	// creditBookingKind is the applied kind of the credit bookings of the regulatory channel.
+	creditBookingKind = "creditBooking"
+)
+
+// collateral is a record of an asset a bank has pledged to the central bank.
+// Haircut is a percentage taken off the value when computing credit capacity.
+type collateral struct {
+	ID      string `json:"ID"`
+	BankID  string `json:"bankID"`
+	Value   int    `json:"value"`
+	Haircut int    `json:"haircut"`
+	Date    string `json:"date"`
+}
+
+// creditLine is the intraday credit the central bank has granted to a bank.
+// Rate is the overnight interest rate in basis points.
+type creditLine struct {
+	ID     string `json:"ID"`
+	BankID string `json:"bankID"`
+	Limit  int    `json:"limit"`
+	Rate   int    `json:"rate"`
+	Date   string `json:"date"`
+}
+
+// creditBooking is a movement of intraday credit committed on the regulatory channel.
+type creditBooking struct {
+	ID       string `json:"ID"`
+	BankID   string `json:"bankID"`
+	Drawn    int    `json:"drawn"`
+	Repaid   int    `json:"repaid"`
+	Interest int    `json:"interest"`
+	Date     string `json:"date"`
+}
+
+// appliedRecord marks a record committed on another channel as applied here.
+type appliedRecord struct {
+	Kind string `json:"kind"`
+	ID   string `json:"ID"`
+	TxID string `json:"txID"`
+}
+
+// txDate returns the transaction timestamp in the format used by history records.
+func txDate(ctx contractapi.TransactionContextInterface) (string, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format("2006-01-02 15:04"), nil
+}
+
+// PledgeCollateral records a collateral a bank pledges against its credit line.
+func (s *AdminContract) PledgeCollateral(ctx contractapi.TransactionContextInterface, bankID string, collateralID string, value string, haircut string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(collateralType, []string{bankID, collateralID})
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the collateral %s already exists", collateralID)
+	}
+
+	valueNum, e := strconv.Atoi(value)
+	if e != nil {
+		return e
+	}
+	haircutNum, e := strconv.Atoi(haircut)
+	if e != nil {
+		return e
+	}
+	if valueNum <= 0 || haircutNum < 0 || haircutNum > 100 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid collateral value or haircut")
+	}
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	col := collateral{
+		ID:      collateralID,
+		BankID:  bankID,
+		Value:   valueNum,
+		Haircut: haircutNum,
+		Date:    date,
+	}
+	colJSON, err := json.Marshal(col)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, colJSON)
+}
+
+// ReleaseCollateral returns a pledged collateral if the rest still covers the credit line.
+func (s *AdminContract) ReleaseCollateral(ctx contractapi.TransactionContextInterface, bankID string, collateralID string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(collateralType, []string{bankID, collateralID})
+	if err != nil {
+		return err
+	}
+	colJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if colJSON == nil {
+		return cbdcerr.New(cbdcerr.NotFound, "the collateral %s does not exist", collateralID)
+	}
+	var col collateral
+	err = json.Unmarshal(colJSON, &col)
+	if err != nil {
+		return err
+	}
+
+	capacity, err := s.collateralCapacity(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	line, err := s.readCreditLine(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	if line != nil && capacity-col.Value*(100-col.Haircut)/100 < line.Limit {
+		return cbdcerr.New(cbdcerr.Rejected, "the collateral %s is needed to cover the credit line of %s", collateralID, bankID)
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// ReadCollaterals returns all collaterals pledged by a bank.
+func (s *AdminContract) ReadCollaterals(ctx contractapi.TransactionContextInterface, bankID string) ([]*collateral, error) {
+	colJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(collateralType, []string{bankID})
+	if err != nil {
+		return nil, err
+	}
+	defer colJSON.Close()
+	var cols []*collateral
+	for colJSON.HasNext() {
+		queryResponse, err := colJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var col collateral
+		err = json.Unmarshal(queryResponse.Value, &col)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, &col)
+	}
+	return cols, nil
+}
+
+// collateralCapacity is the credit a bank can get from its pledged collaterals after haircut.
+func (s *AdminContract) collateralCapacity(ctx contractapi.TransactionContextInterface, bankID string) (int, error) {
+	cols, err := s.ReadCollaterals(ctx, bankID)
+	if err != nil {
+		return 0, err
+	}
+	capacity := 0
+	for _, col := range cols {
+		capacity = capacity + col.Value*(100-col.Haircut)/100
+	}
+	return capacity, nil
+}
+
+// GrantCreditLine grants an intraday credit line to a bank up to its collateral capacity.
+// The grant only takes effect once the regulator applies it to the bank's account
+// with SetCreditLine on the regulatory channel, which checks it against this record.
+func (s *AdminContract) GrantCreditLine(ctx contractapi.TransactionContextInterface, bankID string, limit string, rate string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can grant credit lines")
+	}
+	limitNum, e := strconv.Atoi(limit)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid limit %q", limit)
+	}
+	rateNum, e := strconv.Atoi(rate)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid rate %q", rate)
+	}
+	if limitNum < 0 || rateNum < 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "credit limit and rate must not be negative")
+	}
+
+	capacity, err := s.collateralCapacity(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	if limitNum > capacity {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "credit limit %d exceeds the collateral value %d of %s", limitNum, capacity, bankID)
+	}
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(creditLineType, []string{bankID})
+	if err != nil {
+		return err
+	}
+	line := creditLine{
+		ID:     key,
+		BankID: bankID,
+		Limit:  limitNum,
+		Rate:   rateNum,
+		Date:   date,
+	}
+	lineJSON, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, lineJSON)
+}
+
+// ReadCreditLine returns the credit line granted to a bank.
+func (s *AdminContract) ReadCreditLine(ctx contractapi.TransactionContextInterface, bankID string) (*creditLine, error) {
+	line, err := s.readCreditLine(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	if line == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the credit line of %s does not exist", bankID)
+	}
+	return line, nil
+}
+
+func (s *AdminContract) readCreditLine(ctx contractapi.TransactionContextInterface, bankID string) (*creditLine, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(creditLineType, []string{bankID})
+	if err != nil {
+		return nil, err
+	}
+	lineJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if lineJSON == nil {
+		return nil, nil
+	}
+	var line creditLine
+	err = json.Unmarshal(lineJSON, &line)
+	if err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// BookCredit applies a credit booking the regulatory chaincode committed to the total
+// balance. Credit drawn is newly issued and repaid credit is retired; the interest is
+// paid to the central bank, so it is added to the undistributed balance while the
+// total issued stays the same. Each booking is applied once.
+func (s *AdminContract) BookCredit(ctx contractapi.TransactionContextInterface, bookingID string) (*creditBooking, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can book credit")
+	}
+	// 규제 채널에 커밋된 기록만 반영
+	payload, err := queryRegulatory(ctx, "ReadCreditBooking", bookingID)
+	if err != nil {
+		return nil, err
+	}
+	var booking creditBooking
+	err = json.Unmarshal(payload, &booking)
+	if err != nil {
+		return nil, err
+	}
+	err = s.markApplied(ctx, creditBookingKind, bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	bal, err := s.ReadTotalBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	net := booking.Drawn - booking.Repaid
+	bal.TBalance = bal.TBalance + net
+	if bal.TBalance > MAX_VAL {
+		return nil, cbdcerr.New(cbdcerr.LimitExceeded, "the total balance cannot exceed %d", MAX_VAL)
+	}
+	bal.Balance = bal.Balance + booking.Interest
+	// 발행 이력에는 신규 발행·환수분만 기록
+	if net != 0 {
+		s.TransferHistory(ctx, "credit:"+booking.BankID, strconv.Itoa(net))
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(bal.ID, balJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// queryRegulatory evaluates a function of the regulatory chaincode and returns its payload.
+// Anything the function writes is discarded.
+func queryRegulatory(ctx contractapi.TransactionContextInterface, params ...string) ([]byte, error) {
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return nil, cbdcerr.FromResponse(response)
+	}
+	return response.Payload, nil
+}
+
+// markApplied records that the record kind/id of another channel has been applied.
+// It fails if it already was.
+func (s *AdminContract) markApplied(ctx contractapi.TransactionContextInterface, kind string, id string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(appliedType, []string{kind, id})
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the %s %s has already been applied", kind, id)
+	}
+	recordJSON, err := json.Marshal(appliedRecord{Kind: kind, ID: id, TxID: ctx.GetStub().GetTxID()})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, recordJSON)
+}
@@ -0,0 +1,276 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	currencyType        = "currency"
+	currencyAccountType = "currencyAccount"
+)
+
+// currencyAccount is what a holder, such as a domestic or a foreign bank, owns in a currency ledger.
+// It is a corridor account, the one PvP swap legs are escrowed from and paid to, and is separate
+// from the bank's account on the regulatory channel. In the domestic CBDC_NAME ledger, too, it is
+// funded with IssueCurrency out of the CBDC the central bank holds and not yet transferred to
+// banks, and RedeemCurrency returns it there, so the corridor never holds more than was issued.
+type currencyAccount struct {
+	Currency string `json:"currency"`
+	Holder   string `json:"holder"`
+	Balance  int    `json:"balance"`
+}
+
+// Each currency ledger keeps its issuance in a totalBalance stored under the currency name,
+// like the domestic CBDC_NAME ledger: Balance is held by the issuing central bank and
+// TBalance is the total issued. The other currencies are listed under currencyType keys.
+
+// CreateCurrency opens the ledger of another currency, such as a foreign CBDC network
+// simulated locally for the cross-border corridor.
+func (s *AdminContract) CreateCurrency(ctx contractapi.TransactionContextInterface, code string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can create currencies")
+	}
+	if code == "" {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "currency code is required")
+	}
+	for _, c := range code {
+		if c < 'a' || c > 'z' {
+			return cbdcerr.New(cbdcerr.InvalidArgument, "currency code must be lower case letters: %s", code)
+		}
+	}
+	if _, err := s.ReadCurrency(ctx, code); err == nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the currency %s already exists", code)
+	}
+
+	balJSON, err := json.Marshal(totalBalance{ID: code})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(code, balJSON)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(currencyType, []string{code})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(code))
+}
+
+// ReadCurrency returns the issuance of a currency ledger.
+func (s *AdminContract) ReadCurrency(ctx contractapi.TransactionContextInterface, code string) (*totalBalance, error) {
+	if code != CBDC_NAME {
+		key, err := ctx.GetStub().CreateCompositeKey(currencyType, []string{code})
+		if err != nil {
+			return nil, err
+		}
+		listed, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if listed == nil {
+			return nil, cbdcerr.New(cbdcerr.NotFound, "the currency %s does not exist", code)
+		}
+	}
+	balJSON, err := ctx.GetStub().GetState(code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if balJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the currency %s does not exist", code)
+	}
+	var bal totalBalance
+	err = json.Unmarshal(balJSON, &bal)
+	if err != nil {
+		return nil, err
+	}
+	return &bal, nil
+}
+
+// ReadCurrencies returns the issuance of every currency ledger, the domestic one first.
+func (s *AdminContract) ReadCurrencies(ctx contractapi.TransactionContextInterface) ([]*totalBalance, error) {
+	var balances []*totalBalance
+	if bal, err := s.ReadCurrency(ctx, CBDC_NAME); err == nil {
+		balances = append(balances, bal)
+	}
+	currencyJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(currencyType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer currencyJSON.Close()
+	for currencyJSON.HasNext() {
+		queryResponse, err := currencyJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		bal, err := s.ReadCurrency(ctx, string(queryResponse.Value))
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, bal)
+	}
+	return balances, nil
+}
+
+// MintCurrency issues amount of a currency to its central bank.
+func (s *AdminContract) MintCurrency(ctx contractapi.TransactionContextInterface, code string, amount int) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can mint %s", code)
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	bal, err := s.ReadCurrency(ctx, code)
+	if err != nil {
+		return err
+	}
+	bal.Balance = bal.Balance + amount
+	bal.TBalance = bal.TBalance + amount
+	if bal.TBalance > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "the total balance cannot exceed %d", MAX_VAL)
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return err
+	}
+	s.currencyHistory(ctx, code, code, strconv.Itoa(amount))
+	return ctx.GetStub().PutState(code, balJSON)
+}
+
+// IssueCurrency pays amount of a currency from its central bank to a holder's account.
+func (s *AdminContract) IssueCurrency(ctx contractapi.TransactionContextInterface, code string, holder string, amount int) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can issue %s", code)
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	bal, err := s.ReadCurrency(ctx, code)
+	if err != nil {
+		return err
+	}
+	if bal.Balance < amount {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+	}
+	account, err := s.readCurrencyAccount(ctx, code, holder)
+	if err != nil {
+		return err
+	}
+	bal.Balance = bal.Balance - amount
+	account.Balance = account.Balance + amount
+
+	err = s.putCurrencyAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return err
+	}
+	s.currencyHistory(ctx, code, holder, strconv.Itoa(amount))
+	return ctx.GetStub().PutState(code, balJSON)
+}
+
+// RedeemCurrency returns amount of a holder's corridor account to the central bank of its currency.
+func (s *AdminContract) RedeemCurrency(ctx contractapi.TransactionContextInterface, code string, holder string, amount int) error {
+	if !isBankOperator(ctx, holder) && !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", holder)
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	bal, err := s.ReadCurrency(ctx, code)
+	if err != nil {
+		return err
+	}
+	account, err := s.readCurrencyAccount(ctx, code, holder)
+	if err != nil {
+		return err
+	}
+	if account.Balance < amount {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+	}
+	account.Balance = account.Balance - amount
+	bal.Balance = bal.Balance + amount
+
+	err = s.putCurrencyAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return err
+	}
+	s.currencyHistory(ctx, code, holder, strconv.Itoa(-amount))
+	return ctx.GetStub().PutState(code, balJSON)
+}
+
+// ReadCurrencyAccount returns what holder owns in a currency ledger.
+func (s *AdminContract) ReadCurrencyAccount(ctx contractapi.TransactionContextInterface, code string, holder string) (*currencyAccount, error) {
+	if _, err := s.ReadCurrency(ctx, code); err != nil {
+		return nil, err
+	}
+	return s.readCurrencyAccount(ctx, code, holder)
+}
+
+func (s *AdminContract) readCurrencyAccount(ctx contractapi.TransactionContextInterface, code string, holder string) (*currencyAccount, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(currencyAccountType, []string{code, holder})
+	if err != nil {
+		return nil, err
+	}
+	accountJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	account := currencyAccount{Currency: code, Holder: holder}
+	if accountJSON == nil {
+		return &account, nil
+	}
+	err = json.Unmarshal(accountJSON, &account)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (s *AdminContract) putCurrencyAccount(ctx contractapi.TransactionContextInterface, account *currencyAccount) error {
+	key, err := ctx.GetStub().CreateCompositeKey(currencyAccountType, []string{account.Currency, account.Holder})
+	if err != nil {
+		return err
+	}
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, accountJSON)
+}
+
+// currencyHistory records an issue or a redemption in a currency ledger.
+func (s *AdminContract) currencyHistory(ctx contractapi.TransactionContextInterface, code string, bankID string, price string) error {
+	history, err := s.ReadTransferHistory(ctx)
+	if err != nil {
+		return err
+	}
+	id := strconv.Itoa((len(history) + 1))
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	his := issueHistory{
+		ID:       id,
+		BankID:   bankID,
+		Price:    price,
+		Date:     date,
+		Currency: code,
+	}
+	hisJSON, err := json.Marshal(his)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, hisJSON)
+}
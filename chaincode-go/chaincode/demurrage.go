@@ -0,0 +1,206 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	lotIssueType   = "lotIssue"
+	lotsReconciled = "lotsReconciled"
+)
+
+// lotSweep is CBDC the user chaincode took off expiring lots. Expired is what expired lots,
+// and lots their account could not take, returned to the issuer and Demurrage what live lots lost.
+type lotSweep struct {
+	Accounts  int    `json:"accounts"`
+	Expired   int    `json:"expired"`
+	Demurrage int    `json:"demurrage"`
+	Date      string `json:"date"`
+}
+
+// lotIssue is CBDC issued for a stimulus programme, to be credited to the user as an
+// expiring lot by IssueBalanceLot on the user channel.
+type lotIssue struct {
+	ID        string `json:"ID"`
+	UserID    string `json:"userID"`
+	Amount    int    `json:"amount"`
+	Expiry    int64  `json:"expiry"`
+	Demurrage int    `json:"demurrage"`
+	Date      string `json:"date"`
+}
+
+// IssueExpiringBalance issues new CBDC straight to a user for a stimulus programme.
+// It expires at expiry (unix seconds) unless spent, and until then loses demurrage
+// annual basis points of its value at each sweep. The amount is added to the total
+// issued and the issue recorded under issueID; the user chaincode credits it with
+// IssueBalanceLot.
+func (s *AdminContract) IssueExpiringBalance(ctx contractapi.TransactionContextInterface, issueID string, userID string, balance string, expiry int64, demurrage int) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can issue expiring balances")
+	}
+	existing, err := s.readLotIssue(ctx, issueID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the lot issue %s already exists", issueID)
+	}
+	bal, err := s.ReadTotalBalance(ctx)
+	if err != nil {
+		return err
+	}
+	balNum, e := strconv.Atoi(balance)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid balance %q", balance)
+	}
+	if balNum <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "balance must be positive")
+	}
+	if demurrage < 0 || demurrage > 10000 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "demurrage must be between 0 and 10000 basis points")
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if expiry <= ts.Seconds {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+	bal.TBalance = bal.TBalance + balNum
+	if bal.TBalance > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "the total balance cannot exceed %d", MAX_VAL)
+	}
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	issue := lotIssue{
+		ID:        issueID,
+		UserID:    userID,
+		Amount:    balNum,
+		Expiry:    expiry,
+		Demurrage: demurrage,
+		Date:      date,
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(lotIssueType, []string{issueID})
+	if err != nil {
+		return err
+	}
+	issueJSON, err := json.Marshal(issue)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, issueJSON)
+	if err != nil {
+		return err
+	}
+
+	s.TransferHistory(ctx, userID, balance)
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(bal.ID, balJSON)
+}
+
+// ReadLotIssue returns the expiring balance issued under issueID.
+func (s *AdminContract) ReadLotIssue(ctx contractapi.TransactionContextInterface, issueID string) (*lotIssue, error) {
+	issue, err := s.readLotIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the lot issue %s does not exist", issueID)
+	}
+	return issue, nil
+}
+
+func (s *AdminContract) readLotIssue(ctx contractapi.TransactionContextInterface, issueID string) (*lotIssue, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(lotIssueType, []string{issueID})
+	if err != nil {
+		return nil, err
+	}
+	issueJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if issueJSON == nil {
+		return nil, nil
+	}
+	var issue lotIssue
+	err = json.Unmarshal(issueJSON, &issue)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// ReconcileLotSweeps retires from the total issued what the user chaincode swept off
+// expiring lots since the last reconciliation, and returns it.
+func (s *AdminContract) ReconcileLotSweeps(ctx contractapi.TransactionContextInterface) (*lotSweep, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can reconcile sweeps")
+	}
+	bal, err := s.ReadTotalBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := queryUser(ctx, "ReadSweepTotals")
+	if err != nil {
+		return nil, err
+	}
+	var totals lotSweep
+	err = json.Unmarshal(payload, &totals)
+	if err != nil {
+		return nil, err
+	}
+	var reconciled lotSweep
+	reconciledJSON, err := ctx.GetStub().GetState(lotsReconciled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if reconciledJSON != nil {
+		err = json.Unmarshal(reconciledJSON, &reconciled)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sweep := lotSweep{
+		Accounts:  totals.Accounts - reconciled.Accounts,
+		Expired:   totals.Expired - reconciled.Expired,
+		Demurrage: totals.Demurrage - reconciled.Demurrage,
+		Date:      totals.Date,
+	}
+	if totals == reconciled {
+		return &sweep, nil
+	}
+	// 만료·감가된 CBDC 는 회수되어 총 발행량에서 차감
+	swept := sweep.Expired + sweep.Demurrage
+	bal.TBalance = bal.TBalance - swept
+	if swept != 0 {
+		s.TransferHistory(ctx, "sweep", strconv.Itoa(-swept))
+	}
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(lotsReconciled, totalsJSON)
+	if err != nil {
+		return nil, err
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(bal.ID, balJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &sweep, nil
+}
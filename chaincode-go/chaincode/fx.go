@@ -0,0 +1,150 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	fxRateType = "fxRate"
+
+	// fxScale is the fixed point of FX rates: a rate of fxScale is one to one.
+	fxScale = 1000000
+
+	// fxMaxAge is how long, in seconds, a rate can be used for conversion after it was set.
+	fxMaxAge = 10 * 60
+)
+
+// FXRate is the price of one unit of Base in units of Quote, scaled by fxScale,
+// as last set by the FX oracle at UpdatedAt (unix seconds).
+type FXRate struct {
+	Base      string `json:"base"`
+	Quote     string `json:"quote"`
+	Rate      int64  `json:"rate"`
+	UpdatedAt int64  `json:"updatedAt"`
+	Date      string `json:"date"`
+}
+
+// SetFXRate records the rate of a currency pair. Only the FX oracle can set rates.
+func (s *AdminContract) SetFXRate(ctx contractapi.TransactionContextInterface, base string, quote string, rate int64) error {
+	if !isFXOracle(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the FX oracle can set rates")
+	}
+	if base == quote {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "base and quote must differ")
+	}
+	if rate <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "rate must be positive")
+	}
+	if _, err := s.ReadCurrency(ctx, base); err != nil {
+		return err
+	}
+	if _, err := s.ReadCurrency(ctx, quote); err != nil {
+		return err
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 역방향 시세가 남아 있으면 오래된 값이 쓰이지 않도록 삭제
+	inverse, err := ctx.GetStub().CreateCompositeKey(fxRateType, []string{quote, base})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(inverse)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(fxRateType, []string{base, quote})
+	if err != nil {
+		return err
+	}
+	rateJSON, err := json.Marshal(FXRate{Base: base, Quote: quote, Rate: rate, UpdatedAt: ts.Seconds, Date: date})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, rateJSON)
+}
+
+// ReadFXRate returns the rate of base in quote, inverting the rate of the opposite pair
+// if that is the one the oracle set.
+func (s *AdminContract) ReadFXRate(ctx contractapi.TransactionContextInterface, base string, quote string) (*FXRate, error) {
+	rate, err := s.readFXRate(ctx, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	if rate != nil {
+		return rate, nil
+	}
+	rate, err = s.readFXRate(ctx, quote, base)
+	if err != nil {
+		return nil, err
+	}
+	if rate == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "no rate for %s/%s", base, quote)
+	}
+	return &FXRate{
+		Base:      base,
+		Quote:     quote,
+		Rate:      fxScale * fxScale / rate.Rate,
+		UpdatedAt: rate.UpdatedAt,
+		Date:      rate.Date,
+	}, nil
+}
+
+func (s *AdminContract) readFXRate(ctx contractapi.TransactionContextInterface, base string, quote string) (*FXRate, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(fxRateType, []string{base, quote})
+	if err != nil {
+		return nil, err
+	}
+	rateJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if rateJSON == nil {
+		return nil, nil
+	}
+	var rate FXRate
+	err = json.Unmarshal(rateJSON, &rate)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// convert returns amount of base in quote at the current rate, rounded down.
+// Rates older than fxMaxAge are refused.
+func (s *AdminContract) convert(ctx contractapi.TransactionContextInterface, base string, quote string, amount int) (int, *FXRate, error) {
+	rate, err := s.ReadFXRate(ctx, base, quote)
+	if err != nil {
+		return 0, nil, err
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, nil, err
+	}
+	if ts.Seconds-rate.UpdatedAt > fxMaxAge {
+		return 0, nil, cbdcerr.New(cbdcerr.Rejected, "the %s/%s rate set at %s is stale", base, quote, rate.Date)
+	}
+	converted := int64(amount) * rate.Rate / fxScale
+	// 역방향 시세로 저장된 경우 역수의 반올림 오차를 피하기 위해 원래 시세로 나눔
+	stored, err := s.readFXRate(ctx, quote, base)
+	if err != nil {
+		return 0, nil, err
+	}
+	if stored != nil {
+		converted = int64(amount) * fxScale / stored.Rate
+	}
+	if converted <= 0 || converted > int64(MAX_VAL) {
+		return 0, nil, cbdcerr.New(cbdcerr.InvalidArgument, "%d %s converts to %d %s", amount, base, converted, quote)
+	}
+	return int(converted), rate, nil
+}
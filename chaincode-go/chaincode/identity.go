@@ -0,0 +1,33 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	centralbankMSP = "centralbankOrg"
+
+	// bankAttr is the Fabric CA enrollment attribute naming the bank account a client operates.
+	bankAttr = "bankID"
+
+	// fxOracleAttr is the Fabric CA enrollment attribute, set to "true", of the FX rate oracle.
+	fxOracleAttr = "fxOracle"
+)
+
+// isBankOperator reports whether the client is enrolled as an operator of bank id.
+func isBankOperator(ctx contractapi.TransactionContextInterface, id string) bool {
+	bank, found, err := ctx.GetClientIdentity().GetAttributeValue(bankAttr)
+	return err == nil && found && bank == id
+}
+
+// isRegulator reports whether the client belongs to the central bank.
+func isRegulator(ctx contractapi.TransactionContextInterface) bool {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	return err == nil && mspID == centralbankMSP
+}
+
+// isFXOracle reports whether the client is enrolled as the FX rate oracle.
+func isFXOracle(ctx contractapi.TransactionContextInterface) bool {
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(fxOracleAttr)
+	return err == nil && found && value == "true"
+}
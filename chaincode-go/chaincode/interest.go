@@ -0,0 +1,171 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	rateScheduleKey    = "rateSchedule"
+	interestReconciled = "interestReconciled"
+)
+
+// RateTier applies Rate, in annual basis points, to the part of a balance above Threshold
+// up to the threshold of the next tier. A negative rate is a holding fee.
+type RateTier struct {
+	Threshold int `json:"threshold"`
+	Rate      int `json:"rate"`
+}
+
+// rateSchedule is the remuneration of user holdings. Since is when it took effect, in unix seconds.
+type rateSchedule struct {
+	Tiers []*RateTier `json:"tiers"`
+	Since int64       `json:"since"`
+	Date  string      `json:"date"`
+}
+
+// interestPosting is interest the user chaincode posted to user balances.
+// Paid is the interest credited and Charged the holding fees debited.
+type interestPosting struct {
+	Accounts int    `json:"accounts"`
+	Paid     int    `json:"paid"`
+	Charged  int    `json:"charged"`
+	Date     string `json:"date"`
+}
+
+// SetRateSchedule sets the interest paid on, or the holding fee charged for, user balances.
+// Tiers must be in increasing order of threshold. Interest accrues on the user channel,
+// which takes the schedule from here with its own SetRateSchedule. Interest accrued and
+// not yet computed is computed at the new rates, so accounts should be posted with
+// PostAccountInterest on the user channel before the rates change.
+func (s *AdminContract) SetRateSchedule(ctx contractapi.TransactionContextInterface, tiers []*RateTier) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set interest rates")
+	}
+	for i, tier := range tiers {
+		if tier.Threshold < 0 || (i > 0 && tier.Threshold <= tiers[i-1].Threshold) {
+			return cbdcerr.New(cbdcerr.InvalidArgument, "tier thresholds must be positive and increasing")
+		}
+		if tier.Rate < -10000 || tier.Rate > 10000 {
+			return cbdcerr.New(cbdcerr.InvalidArgument, "rate %d is outside -10000 to 10000 basis points", tier.Rate)
+		}
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	schedule := rateSchedule{Tiers: tiers, Since: ts.Seconds, Date: date}
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(rateScheduleKey, scheduleJSON)
+}
+
+// ReadRateSchedule returns the current rate schedule. Before one is set no interest accrues.
+func (s *AdminContract) ReadRateSchedule(ctx contractapi.TransactionContextInterface) (*rateSchedule, error) {
+	scheduleJSON, err := ctx.GetStub().GetState(rateScheduleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	var schedule rateSchedule
+	if scheduleJSON == nil {
+		return &schedule, nil
+	}
+	err = json.Unmarshal(scheduleJSON, &schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// ReconcileInterest adds the interest the user chaincode posted since the last
+// reconciliation to the total issued and returns it. Interest paid is newly issued
+// and holding fees are withdrawn, so the net amount is recorded in the issue history.
+func (s *AdminContract) ReconcileInterest(ctx contractapi.TransactionContextInterface) (*interestPosting, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can reconcile interest")
+	}
+	bal, err := s.ReadTotalBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := queryUser(ctx, "ReadInterestTotals")
+	if err != nil {
+		return nil, err
+	}
+	var totals interestPosting
+	err = json.Unmarshal(payload, &totals)
+	if err != nil {
+		return nil, err
+	}
+	var reconciled interestPosting
+	reconciledJSON, err := ctx.GetStub().GetState(interestReconciled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if reconciledJSON != nil {
+		err = json.Unmarshal(reconciledJSON, &reconciled)
+		if err != nil {
+			return nil, err
+		}
+	}
+	posting := interestPosting{
+		Accounts: totals.Accounts - reconciled.Accounts,
+		Paid:     totals.Paid - reconciled.Paid,
+		Charged:  totals.Charged - reconciled.Charged,
+		Date:     totals.Date,
+	}
+	if totals == reconciled {
+		return &posting, nil
+	}
+	// 이자 지급은 신규 발행, 보유 수수료는 환수로 총 발행량에 반영
+	net := posting.Paid - posting.Charged
+	bal.TBalance = bal.TBalance + net
+	if bal.TBalance > MAX_VAL {
+		return nil, cbdcerr.New(cbdcerr.LimitExceeded, "the total balance cannot exceed %d", MAX_VAL)
+	}
+	if net != 0 {
+		s.TransferHistory(ctx, "interest", strconv.Itoa(net))
+	}
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(interestReconciled, totalsJSON)
+	if err != nil {
+		return nil, err
+	}
+	balJSON, err := json.Marshal(bal)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(bal.ID, balJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &posting, nil
+}
+
+// queryUser evaluates a function of the user chaincode and returns its payload.
+// Anything the function writes is discarded.
+func queryUser(ctx contractapi.TransactionContextInterface, params ...string) ([]byte, error) {
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("userchaincode", queryArgs, "user-channel")
+	if response.Status != 200 {
+		return nil, cbdcerr.FromResponse(response)
+	}
+	return response.Payload, nil
+}
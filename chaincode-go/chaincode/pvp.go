@@ -0,0 +1,274 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/cbdcerr"
+)
+
+const (
+	pvpSwapType = "pvpSwap"
+
+	legPending  = "pending"
+	legLocked   = "locked"
+	legClaimed  = "claimed"
+	legRefunded = "refunded"
+)
+
+// PvPLeg is one side of a swap: Amount of Currency escrowed from From, paid to To with
+// the preimage of the swap's hash lock before Expiry (unix seconds), or refunded after it.
+type PvPLeg struct {
+	Currency string `json:"currency"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Amount   int    `json:"amount"`
+	Expiry   int64  `json:"expiry"`
+	Status   string `json:"status"`
+}
+
+// PvPSwap exchanges two currencies across the corridor with hash-time-locked legs.
+// The initiator locks the first leg and keeps the preimage; the counterparty locks the
+// second leg under the same hash lock with an earlier expiry. The initiator claims the
+// second leg, revealing the preimage, which the counterparty then uses to claim the first.
+// If the preimage is never revealed both legs are refunded, so both currencies move or neither does.
+type PvPSwap struct {
+	ID       string  `json:"ID"`
+	HashLock string  `json:"hashLock"`
+	Preimage string  `json:"preimage,omitempty"`
+	Rate     int64   `json:"rate"`
+	Initial  *PvPLeg `json:"initial"`
+	Counter  *PvPLeg `json:"counter"`
+	Date     string  `json:"date"`
+}
+
+// OpenPvPSwap locks amount of currency from the initiating bank for swapID. The counter leg
+// of counterCurrency is priced from the FX oracle and waits for the counterparty to lock it.
+// hashLock is the hex encoded SHA-256 of the preimage.
+func (s *AdminContract) OpenPvPSwap(ctx contractapi.TransactionContextInterface, swapID string, currency string, from string, counterCurrency string, counterparty string, amount int, hashLock string, expiry int64) error {
+	if !isBankOperator(ctx, from) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", from)
+	}
+	if _, err := hex.DecodeString(hashLock); err != nil || len(hashLock) != sha256.Size*2 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "hashLock must be a hex encoded SHA-256 digest")
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	if from == counterparty {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "a bank cannot swap with itself")
+	}
+	existing, err := s.readPvPSwap(ctx, swapID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the swap %s already exists", swapID)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if expiry <= ts.Seconds {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+	counterAmount, rate, err := s.convert(ctx, currency, counterCurrency, amount)
+	if err != nil {
+		return err
+	}
+	err = s.escrowLeg(ctx, currency, from, amount)
+	if err != nil {
+		return err
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	swap := PvPSwap{
+		ID:       swapID,
+		HashLock: hashLock,
+		Rate:     rate.Rate,
+		Initial:  &PvPLeg{Currency: currency, From: from, To: counterparty, Amount: amount, Expiry: expiry, Status: legLocked},
+		Counter:  &PvPLeg{Currency: counterCurrency, From: counterparty, To: from, Amount: counterAmount, Status: legPending},
+		Date:     date,
+	}
+	return s.putPvPSwap(ctx, &swap)
+}
+
+// LockPvPCounterLeg locks the counterparty's leg of swapID. expiry must come before the
+// initial leg's, leaving the counterparty time to claim it once the preimage is revealed.
+func (s *AdminContract) LockPvPCounterLeg(ctx contractapi.TransactionContextInterface, swapID string, expiry int64) error {
+	swap, err := s.ReadPvPSwap(ctx, swapID)
+	if err != nil {
+		return err
+	}
+	leg := swap.Counter
+	if !isBankOperator(ctx, leg.From) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", leg.From)
+	}
+	if leg.Status != legPending || swap.Initial.Status != legLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the swap %s cannot be locked: initial %s, counter %s", swapID, swap.Initial.Status, leg.Status)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if expiry <= ts.Seconds || expiry >= swap.Initial.Expiry {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future and before %d", swap.Initial.Expiry)
+	}
+	err = s.escrowLeg(ctx, leg.Currency, leg.From, leg.Amount)
+	if err != nil {
+		return err
+	}
+	leg.Expiry = expiry
+	leg.Status = legLocked
+	return s.putPvPSwap(ctx, swap)
+}
+
+// ClaimPvPLeg pays a locked leg ("initial" or "counter") of swapID to its receiver
+// given the preimage of the hash lock, before the leg expires. The preimage is recorded
+// on the swap, from where the other side can read it.
+func (s *AdminContract) ClaimPvPLeg(ctx contractapi.TransactionContextInterface, swapID string, legName string, preimage string) error {
+	swap, err := s.ReadPvPSwap(ctx, swapID)
+	if err != nil {
+		return err
+	}
+	leg, err := swap.leg(legName)
+	if err != nil {
+		return err
+	}
+	if leg.Status != legLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the %s leg of %s is %s", legName, swapID, leg.Status)
+	}
+	digest := sha256.Sum256([]byte(preimage))
+	if hex.EncodeToString(digest[:]) != swap.HashLock {
+		return cbdcerr.New(cbdcerr.Rejected, "the preimage does not match the hash lock")
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if ts.Seconds >= leg.Expiry {
+		return cbdcerr.New(cbdcerr.Rejected, "the %s leg of %s has expired", legName, swapID)
+	}
+	err = s.releaseLeg(ctx, leg.Currency, leg.To, leg.Amount)
+	if err != nil {
+		return err
+	}
+	swap.Preimage = preimage
+	leg.Status = legClaimed
+	return s.putPvPSwap(ctx, swap)
+}
+
+// RefundPvPLeg returns an expired locked leg of swapID to its sender.
+// A pending counter leg is cancelled with it.
+func (s *AdminContract) RefundPvPLeg(ctx contractapi.TransactionContextInterface, swapID string, legName string) error {
+	swap, err := s.ReadPvPSwap(ctx, swapID)
+	if err != nil {
+		return err
+	}
+	leg, err := swap.leg(legName)
+	if err != nil {
+		return err
+	}
+	if leg.Status != legLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the %s leg of %s is %s", legName, swapID, leg.Status)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if ts.Seconds < leg.Expiry {
+		return cbdcerr.New(cbdcerr.Rejected, "the %s leg of %s has not expired", legName, swapID)
+	}
+	err = s.releaseLeg(ctx, leg.Currency, leg.From, leg.Amount)
+	if err != nil {
+		return err
+	}
+	leg.Status = legRefunded
+	if swap.Counter.Status == legPending {
+		swap.Counter.Status = legRefunded
+	}
+	return s.putPvPSwap(ctx, swap)
+}
+
+// ReadPvPSwap returns the swap stored with the given id.
+func (s *AdminContract) ReadPvPSwap(ctx contractapi.TransactionContextInterface, swapID string) (*PvPSwap, error) {
+	swap, err := s.readPvPSwap(ctx, swapID)
+	if err != nil {
+		return nil, err
+	}
+	if swap == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the swap %s does not exist", swapID)
+	}
+	return swap, nil
+}
+
+func (swap *PvPSwap) leg(name string) (*PvPLeg, error) {
+	switch name {
+	case "initial":
+		return swap.Initial, nil
+	case "counter":
+		return swap.Counter, nil
+	}
+	return nil, cbdcerr.New(cbdcerr.InvalidArgument, "leg must be initial or counter: %s", name)
+}
+
+// escrowLeg takes amount of currency from a holder into a swap.
+func (s *AdminContract) escrowLeg(ctx contractapi.TransactionContextInterface, currency string, holder string, amount int) error {
+	account, err := s.ReadCurrencyAccount(ctx, currency, holder)
+	if err != nil {
+		return err
+	}
+	if account.Balance < amount {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+	}
+	account.Balance = account.Balance - amount
+	return s.putCurrencyAccount(ctx, account)
+}
+
+// releaseLeg pays amount of currency held by a swap to a holder.
+func (s *AdminContract) releaseLeg(ctx contractapi.TransactionContextInterface, currency string, holder string, amount int) error {
+	account, err := s.ReadCurrencyAccount(ctx, currency, holder)
+	if err != nil {
+		return err
+	}
+	account.Balance = account.Balance + amount
+	return s.putCurrencyAccount(ctx, account)
+}
+
+func (s *AdminContract) readPvPSwap(ctx contractapi.TransactionContextInterface, swapID string) (*PvPSwap, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pvpSwapType, []string{swapID})
+	if err != nil {
+		return nil, err
+	}
+	swapJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if swapJSON == nil {
+		return nil, nil
+	}
+	var swap PvPSwap
+	err = json.Unmarshal(swapJSON, &swap)
+	if err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+func (s *AdminContract) putPvPSwap(ctx contractapi.TransactionContextInterface, swap *PvPSwap) error {
+	key, err := ctx.GetStub().CreateCompositeKey(pvpSwapType, []string{swap.ID})
+	if err != nil {
+		return err
+	}
+	swapJSON, err := json.Marshal(swap)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, swapJSON)
+}
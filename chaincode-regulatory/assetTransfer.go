@@ -7,7 +7,9 @@ package main
 import (
 	"log"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
 	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
 )
 
@@ -17,7 +19,7 @@ func main() {
 		log.Panicf("Error creating asset-transfer-basic chaincode: %v", err)
 	}
 
-	if err := regulatoryChaincode.Start(); err != nil {
+	if err := shim.Start(cbdcerr.Structured(chaincode.Events(chaincode.Idempotent(chaincode.WatchList(regulatoryChaincode))))); err != nil {
 		log.Panicf("Error starting asset-transfer-basic chaincode: %v", err)
 	}
 }
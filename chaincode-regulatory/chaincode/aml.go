@@ -0,0 +1,253 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	amlRulesKey     = "amlRules"
+	amlActivityType = "amlActivity"
+
+	amlAllow = "allow"
+	amlFlag  = "flag"
+	amlBlock = "block"
+)
+
+// amlRuleSet is the anti-money-laundering configuration kept on the ledger.
+// Every rule that matches a transfer adds its score; the total decides
+// whether the transfer is allowed, flagged or blocked.
+type amlRuleSet struct {
+	Version int `json:"version"`
+
+	// LargeAmount matches a single transfer of at least LargeAmount.
+	LargeAmount      int `json:"largeAmount"`
+	LargeAmountScore int `json:"largeAmountScore"`
+
+	// Structuring matches a user taking part in StructuringCount or more transfers,
+	// or StructuringTotal or more in total, within StructuringWindow seconds.
+	// The user is the sender of user-to-user transfers and the receiver of bank-to-user ones.
+	StructuringWindow int64 `json:"structuringWindow"`
+	StructuringCount  int   `json:"structuringCount"`
+	StructuringTotal  int   `json:"structuringTotal"`
+	StructuringScore  int   `json:"structuringScore"`
+
+	// Blacklist matches a transfer from or to any listed party.
+	Blacklist      []string `json:"blacklist"`
+	BlacklistScore int      `json:"blacklistScore"`
+
+	FlagScore  int `json:"flagScore"`
+	BlockScore int `json:"blockScore"`
+}
+
+// amlResult is the outcome of screening one transfer.
+// Window is the structuring window of the rules, for the user chaincode to prune its activity.
+type amlResult struct {
+	Sender      string   `json:"sender"`
+	Receiver    string   `json:"receiver"`
+	Price       int      `json:"price"`
+	Score       int      `json:"score"`
+	Decision    string   `json:"decision"`
+	Reasons     []string `json:"reasons"`
+	RuleVersion int      `json:"ruleVersion"`
+	Window      int64    `json:"window"`
+}
+
+// amlActivity is a past transfer of a user, checked for structuring.
+type amlActivity struct {
+	Price int   `json:"price"`
+	Time  int64 `json:"time"`
+}
+
+// SetAMLRules replaces the AML rule set. Only the regulator can change it.
+func (s *RegulatoryContract) SetAMLRules(ctx contractapi.TransactionContextInterface, rules string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set AML rules")
+	}
+	var ruleSet amlRuleSet
+	err := json.Unmarshal([]byte(rules), &ruleSet)
+	if err != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid AML rules: %v", err)
+	}
+	if ruleSet.FlagScore <= 0 || ruleSet.BlockScore < ruleSet.FlagScore {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid AML rules: need 0 < flagScore <= blockScore")
+	}
+
+	current, err := s.ReadAMLRules(ctx)
+	if err != nil {
+		return err
+	}
+	ruleSet.Version = current.Version + 1
+	rulesJSON, err := json.Marshal(ruleSet)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(amlRulesKey, rulesJSON)
+}
+
+// ReadAMLRules returns the current AML rule set. Before any is set no rule matches.
+func (s *RegulatoryContract) ReadAMLRules(ctx contractapi.TransactionContextInterface) (*amlRuleSet, error) {
+	rulesJSON, err := ctx.GetStub().GetState(amlRulesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	var ruleSet amlRuleSet
+	if rulesJSON == nil {
+		return &ruleSet, nil
+	}
+	err = json.Unmarshal(rulesJSON, &ruleSet)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleSet, nil
+}
+
+// ScreenTransfer scores a user-to-user transfer against the AML rules. activity is the
+// sender's past transfers, a JSON list of amlActivity the user chaincode keeps and passes
+// when it queries this; nothing is recorded here.
+func (s *RegulatoryContract) ScreenTransfer(ctx contractapi.TransactionContextInterface, sender string, rec string, price string, activity string) (*amlResult, error) {
+	priceNum, e := strconv.Atoi(price)
+	if e != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid price %q", price)
+	}
+	var past []*amlActivity
+	if activity != "" {
+		err := json.Unmarshal([]byte(activity), &past)
+		if err != nil {
+			return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid AML activity: %v", err)
+		}
+	}
+	ruleSet, err := s.ReadAMLRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	return scoreTransfer(ruleSet, sender, rec, priceNum, past, ts.Seconds), nil
+}
+
+// checkTransfer screens a bank-to-user transfer made inside this chaincode and records it
+// in the receiver's activity. A flagged transfer emits a SuspiciousActivity event, a blocked one fails.
+func (s *RegulatoryContract) checkTransfer(ctx contractapi.TransactionContextInterface, sender string, rec string, price int) error {
+	ruleSet, err := s.ReadAMLRules(ctx)
+	if err != nil {
+		return err
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	now := ts.Seconds
+	past, err := s.recentActivity(ctx, rec, now-ruleSet.StructuringWindow)
+	if err != nil {
+		return err
+	}
+	result := scoreTransfer(ruleSet, sender, rec, price, past, now)
+	switch result.Decision {
+	case amlBlock:
+		return cbdcerr.New(cbdcerr.Blocked, "transfer from %s to %s blocked by AML rules: %v", result.Sender, result.Receiver, result.Reasons)
+	case amlFlag:
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		err = emitEvent(ctx, "SuspiciousActivity", resultJSON)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(amlActivityType, []string{rec, ctx.GetStub().GetTxID()})
+	if err != nil {
+		return err
+	}
+	activityJSON, err := json.Marshal(amlActivity{Price: price, Time: now})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, activityJSON)
+}
+
+// scoreTransfer scores a transfer at now, given the past transfers of the user checked for structuring.
+func scoreTransfer(ruleSet *amlRuleSet, sender string, rec string, price int, past []*amlActivity, now int64) *amlResult {
+	result := amlResult{
+		Sender:      sender,
+		Receiver:    rec,
+		Price:       price,
+		Decision:    amlAllow,
+		Reasons:     []string{},
+		RuleVersion: ruleSet.Version,
+		Window:      ruleSet.StructuringWindow,
+	}
+
+	if ruleSet.LargeAmount > 0 && price >= ruleSet.LargeAmount {
+		result.Score = result.Score + ruleSet.LargeAmountScore
+		result.Reasons = append(result.Reasons, "large amount")
+	}
+
+	for _, party := range ruleSet.Blacklist {
+		if party == sender || party == rec {
+			result.Score = result.Score + ruleSet.BlacklistScore
+			result.Reasons = append(result.Reasons, "blacklisted party "+party)
+			break
+		}
+	}
+
+	if ruleSet.StructuringWindow > 0 {
+		count, total := 1, price
+		for _, activity := range past {
+			if activity.Time >= now-ruleSet.StructuringWindow {
+				count = count + 1
+				total = total + activity.Price
+			}
+		}
+		if (ruleSet.StructuringCount > 0 && count >= ruleSet.StructuringCount) ||
+			(ruleSet.StructuringTotal > 0 && total >= ruleSet.StructuringTotal) {
+			result.Score = result.Score + ruleSet.StructuringScore
+			result.Reasons = append(result.Reasons, "structuring")
+		}
+	}
+
+	if ruleSet.BlockScore > 0 && result.Score >= ruleSet.BlockScore {
+		result.Decision = amlBlock
+	} else if ruleSet.FlagScore > 0 && result.Score >= ruleSet.FlagScore {
+		result.Decision = amlFlag
+	}
+	return &result
+}
+
+// recentActivity returns the user's transfers since from and prunes older ones.
+func (s *RegulatoryContract) recentActivity(ctx contractapi.TransactionContextInterface, user string, from int64) ([]*amlActivity, error) {
+	activityJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(amlActivityType, []string{user})
+	if err != nil {
+		return nil, err
+	}
+	defer activityJSON.Close()
+	var past []*amlActivity
+	for activityJSON.HasNext() {
+		queryResponse, err := activityJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var activity amlActivity
+		err = json.Unmarshal(queryResponse.Value, &activity)
+		if err != nil {
+			return nil, err
+		}
+		if activity.Time < from {
+			err = ctx.GetStub().DelState(queryResponse.Key)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		past = append(past, &activity)
+	}
+	return past, nil
+}
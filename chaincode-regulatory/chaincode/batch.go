@@ -0,0 +1,207 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	batchType = "batch"
+
+	linePaid     = "paid"
+	lineRejected = "rejected"
+)
+
+// BatchLine is one payment of a batch.
+type BatchLine struct {
+	UserID string `json:"userID"`
+	Amount int    `json:"amount"`
+}
+
+// batchResult is the outcome of one line of a batch.
+type batchResult struct {
+	UserID string       `json:"userID"`
+	Amount int          `json:"amount"`
+	Status string       `json:"status"`
+	Code   cbdcerr.Code `json:"code,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// batchSummary is the record kept for each batch.
+type batchSummary struct {
+	ID           string         `json:"ID"`
+	BankID       string         `json:"bankID"`
+	AllOrNothing bool           `json:"allOrNothing"`
+	Lines        int            `json:"lines"`
+	Paid         int            `json:"paid"`
+	Rejected     int            `json:"rejected"`
+	Total        int            `json:"total"`
+	Date         string         `json:"date"`
+	Results      []*batchResult `json:"results"`
+}
+
+// UpdateSendBalanceBatch pays many users from a bank, for payroll and bulk disbursements.
+// With allOrNothing any rejected line fails the whole batch, otherwise rejected lines are
+// reported and the rest are paid. The lines are checked against the user accounts with
+// ReviewBatch, the bank is debited once for the total paid and the batch is recorded;
+// the regulator then credits the users with UpdateAccountBatch on the user channel.
+func (s *RegulatoryContract) UpdateSendBalanceBatch(ctx contractapi.TransactionContextInterface, batchID string, id string, lines []*BatchLine, allOrNothing bool) (*batchSummary, error) {
+	if !isBankOperator(ctx, id) && !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only an operator of %s or the regulator can pay a batch from it", id)
+	}
+	if len(lines) == 0 {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "the batch has no lines")
+	}
+	if _, err := s.ReadBatch(ctx, batchID); err == nil {
+		return nil, cbdcerr.New(cbdcerr.AlreadyExists, "the batch %s already exists", batchID)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.screenParties(ctx, id, account.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// 은행 잔액과 남은 일중 신용한도 안에서만 지급
+	available := account.Balance + account.CreditLimit - account.CreditDrawn - account.OvernightLoan
+	results := make([]*batchResult, len(lines))
+	var accepted []*BatchLine
+	var acceptedAt []int
+	reserved := 0
+	for i, line := range lines {
+		result := batchResult{UserID: line.UserID, Amount: line.Amount, Status: linePaid}
+		results[i] = &result
+		var reason *cbdcerr.Error
+		switch {
+		case line.Amount <= 0:
+			reason = &cbdcerr.Error{Code: cbdcerr.InvalidArgument, Message: "amount must be positive"}
+		case reserved+line.Amount > available:
+			reason = &cbdcerr.Error{Code: cbdcerr.InsufficientFunds, Message: "Lack of Balance"}
+		default:
+			if err := s.screenParties(ctx, line.UserID); err != nil {
+				reason = cbdcerr.From(err)
+			} else if err := s.checkTransfer(ctx, id, line.UserID, line.Amount); err != nil {
+				reason = cbdcerr.From(err)
+			}
+		}
+		if reason != nil {
+			if allOrNothing {
+				return nil, cbdcerr.New(reason.Code, "line %d of the batch %s to %s: %s", i+1, batchID, line.UserID, reason.Message)
+			}
+			result.Status = lineRejected
+			result.Code = reason.Code
+			result.Reason = reason.Message
+			continue
+		}
+		reserved = reserved + line.Amount
+		accepted = append(accepted, line)
+		acceptedAt = append(acceptedAt, i)
+	}
+
+	if len(accepted) > 0 {
+		acceptedJSON, err := json.Marshal(accepted)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := queryUser(ctx, "ReviewBatch", string(acceptedJSON), strconv.FormatBool(allOrNothing))
+		if err != nil {
+			return nil, err
+		}
+		var userResults []*batchResult
+		err = json.Unmarshal(payload, &userResults)
+		if err != nil {
+			return nil, err
+		}
+		if len(userResults) != len(accepted) {
+			return nil, cbdcerr.New(cbdcerr.Rejected, "the user chaincode returned %d results for %d lines", len(userResults), len(accepted))
+		}
+		for j, result := range userResults {
+			results[acceptedAt[j]] = result
+		}
+	}
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary := batchSummary{
+		ID:           batchID,
+		BankID:       id,
+		AllOrNothing: allOrNothing,
+		Lines:        len(lines),
+		Date:         date,
+		Results:      results,
+	}
+	for _, result := range results {
+		if result.Status == linePaid {
+			summary.Paid = summary.Paid + 1
+			summary.Total = summary.Total + result.Amount
+		} else {
+			summary.Rejected = summary.Rejected + 1
+		}
+	}
+
+	change := account.Balance - summary.Total
+	if change < 0 {
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, account, -change)
+		if err != nil {
+			return nil, err
+		}
+		if !drawn {
+			return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+		}
+		change = 0
+	}
+	account.Balance = change
+
+	key, err := ctx.GetStub().CreateCompositeKey(batchType, []string{batchID})
+	if err != nil {
+		return nil, err
+	}
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(key, summaryJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	s.TransferHistory(ctx, id, batchType+":"+batchID, strconv.Itoa(summary.Total))
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(id, accountJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (s *RegulatoryContract) ReadBatch(ctx contractapi.TransactionContextInterface, batchID string) (*batchSummary, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(batchType, []string{batchID})
+	if err != nil {
+		return nil, err
+	}
+	summaryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if summaryJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the batch %s does not exist", batchID)
+	}
+	var summary batchSummary
+	err = json.Unmarshal(summaryJSON, &summary)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
@@ -0,0 +1,222 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	disputeType = "dispute"
+
+	disputeOpen      = "open"
+	disputeReversed  = "reversed"
+	disputeDismissed = "dismissed"
+
+	resolutionReversal  = "reversal"
+	resolutionDismissal = "dismissal"
+)
+
+// dispute is a case over an entry of the user chaincode history, filed by the claimant
+// or the regulator. The disputed amount stays on hold in the respondent's account until
+// the regulator resolves it.
+type dispute struct {
+	ID         string               `json:"ID"`
+	HistoryID  string               `json:"historyID"`
+	Claimant   string               `json:"claimant"`
+	Respondent string               `json:"respondent"`
+	Price      int                  `json:"price"`
+	Reason     string               `json:"reason"`
+	Evidence   []string             `json:"evidence"`
+	Status     string               `json:"status"`
+	Transition []*disputeTransition `json:"transition"`
+}
+
+// disputeTransition records who moved a dispute to which status and when.
+type disputeTransition struct {
+	Status string `json:"status"`
+	Actor  string `json:"actor"`
+	Note   string `json:"note"`
+	Date   string `json:"date"`
+}
+
+// userHold is the hold placed for a dispute in the user chaincode.
+type userHold struct {
+	CaseID    string `json:"caseID"`
+	HistoryID string `json:"historyID"`
+	AccountID string `json:"accountID"`
+	Claimant  string `json:"claimant"`
+	Price     int    `json:"price"`
+	Status    string `json:"status"`
+}
+
+// FileDispute opens the dispute caseID over the hold placed for it with PlaceHold in
+// the user chaincode. The claimant of the hold or the regulator can file it.
+func (s *RegulatoryContract) FileDispute(ctx contractapi.TransactionContextInterface, caseID string, reason string) error {
+	existing, err := s.readDispute(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the dispute %s already exists", caseID)
+	}
+
+	payload, err := queryUser(ctx, "ReadHold", caseID)
+	if err != nil {
+		return err
+	}
+	var hold userHold
+	err = json.Unmarshal(payload, &hold)
+	if err != nil {
+		return err
+	}
+	if !isRegulator(ctx) && !isAccountOwner(ctx, hold.Claimant) && !isBankOperator(ctx, hold.Claimant) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s or the regulator can file the dispute %s", hold.Claimant, caseID)
+	}
+	if hold.Status != "open" {
+		return cbdcerr.New(cbdcerr.Rejected, "the hold %s is already %s", caseID, hold.Status)
+	}
+
+	d := dispute{
+		ID:         caseID,
+		HistoryID:  hold.HistoryID,
+		Claimant:   hold.Claimant,
+		Respondent: hold.AccountID,
+		Price:      hold.Price,
+		Reason:     reason,
+		Evidence:   []string{},
+	}
+	return s.moveDispute(ctx, &d, disputeOpen, reason)
+}
+
+// AddDisputeEvidence attaches an evidence reference, such as a document hash or URI, to an open dispute.
+func (s *RegulatoryContract) AddDisputeEvidence(ctx contractapi.TransactionContextInterface, caseID string, evidence string) error {
+	d, err := s.ReadDispute(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if d.Status != disputeOpen {
+		return cbdcerr.New(cbdcerr.Rejected, "the dispute %s is already %s", caseID, d.Status)
+	}
+	d.Evidence = append(d.Evidence, evidence)
+	return s.moveDispute(ctx, d, disputeOpen, "evidence: "+evidence)
+}
+
+// ResolveDispute closes a dispute as a reversal, which returns the held amount to the claimant,
+// or as a dismissal, which releases the hold. The regulator then closes the hold with
+// ReleaseHold in the user chaincode, which follows this resolution.
+// Only the regulator can resolve disputes.
+func (s *RegulatoryContract) ResolveDispute(ctx contractapi.TransactionContextInterface, caseID string, resolution string, note string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can resolve the dispute %s", caseID)
+	}
+	d, err := s.ReadDispute(ctx, caseID)
+	if err != nil {
+		return err
+	}
+	if d.Status != disputeOpen {
+		return cbdcerr.New(cbdcerr.Rejected, "the dispute %s is already %s", caseID, d.Status)
+	}
+
+	var status string
+	switch resolution {
+	case resolutionReversal:
+		status = disputeReversed
+	case resolutionDismissal:
+		status = disputeDismissed
+	default:
+		return cbdcerr.New(cbdcerr.InvalidArgument, "resolution must be %s or %s", resolutionReversal, resolutionDismissal)
+	}
+	return s.moveDispute(ctx, d, status, note)
+}
+
+func (s *RegulatoryContract) ReadDispute(ctx contractapi.TransactionContextInterface, caseID string) (*dispute, error) {
+	d, err := s.readDispute(ctx, caseID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the dispute %s does not exist", caseID)
+	}
+	return d, nil
+}
+
+// ReadDisputesUser returns the disputes where the user is the claimant or the respondent.
+func (s *RegulatoryContract) ReadDisputesUser(ctx contractapi.TransactionContextInterface, userID string) ([]*dispute, error) {
+	disputeJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer disputeJSON.Close()
+	var disputes []*dispute
+	for disputeJSON.HasNext() {
+		queryResponse, err := disputeJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var d dispute
+		err = json.Unmarshal(queryResponse.Value, &d)
+		if err != nil {
+			return nil, err
+		}
+		if d.Claimant == userID || d.Respondent == userID {
+			disputes = append(disputes, &d)
+		}
+	}
+	return disputes, nil
+}
+
+func (s *RegulatoryContract) readDispute(ctx contractapi.TransactionContextInterface, caseID string) (*dispute, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(disputeType, []string{caseID})
+	if err != nil {
+		return nil, err
+	}
+	disputeJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if disputeJSON == nil {
+		return nil, nil
+	}
+	var d dispute
+	err = json.Unmarshal(disputeJSON, &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// moveDispute records a transition of the dispute, stores it and emits it as an event.
+func (s *RegulatoryContract) moveDispute(ctx contractapi.TransactionContextInterface, d *dispute, status string, note string) error {
+	actor, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return err
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	d.Status = status
+	d.Transition = append(d.Transition, &disputeTransition{
+		Status: status,
+		Actor:  actor,
+		Note:   note,
+		Date:   date,
+	})
+
+	disputeJSON, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(disputeType, []string{d.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, disputeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+	return emitEvent(ctx, "Dispute", disputeJSON)
+}
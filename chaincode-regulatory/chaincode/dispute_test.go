@@ -0,0 +1,51 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispute(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	l := newLedger(t, nil)
+	l.peer("userchaincode", "user-channel", answers(map[string]string{
+		"ReadHold": `{"caseID":"case1","historyID":"1","accountID":"User2","claimant":"User1","price":150,"status":"open"}`,
+	}))
+	file := func(c client) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			return s.FileDispute(ctx, "case1", "not delivered")
+		})
+	}
+	resolve := func(c client) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			return s.ResolveDispute(ctx, "case1", "reversal", "refund the claimant")
+		})
+	}
+	status := func() string {
+		var status string
+		require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+			d, err := s.ReadDispute(ctx, "case1")
+			if err != nil {
+				return err
+			}
+			status = d.Status
+			return nil
+		}))
+		return status
+	}
+
+	requireCode(t, file(owner("User2")), cbdcerr.Unauthorized, "only User1 or the regulator can file the dispute case1")
+	require.NoError(t, file(owner("User1")))
+	requireCode(t, file(owner("User1")), cbdcerr.AlreadyExists, "the dispute case1 already exists")
+	require.Equal(t, "open", status())
+
+	// 분쟁 판정은 규제기관만
+	requireCode(t, resolve(owner("User1")), cbdcerr.Unauthorized, "only the regulator can resolve the dispute case1")
+	require.NoError(t, resolve(regulator))
+	require.Equal(t, "reversed", status())
+	requireCode(t, resolve(regulator), cbdcerr.Rejected, "the dispute case1 is already reversed")
+}
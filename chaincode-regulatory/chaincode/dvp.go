@@ -0,0 +1,248 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	dvpType = "dvp"
+
+	// assetChaincode is the asset chaincode on this channel that DvPs earmark assets in.
+	// It is fixed so that a seller cannot point a DvP at a chaincode of its own.
+	assetChaincode = "assetchaincode"
+
+	dvpInstructed = "instructed"
+	dvpSettled    = "settled"
+	dvpCancelled  = "cancelled"
+)
+
+// DvP is a delivery-versus-payment of Quantity of an asset, kept in the asset chaincode
+// assetchaincode on this channel, from Seller to Buyer against Price in CBDC.
+// The seller instructs it, which earmarks the asset for the buyer; the buyer settles it,
+// which pays the price and releases the earmark in the same transaction, so either both
+// legs commit or neither does. The asset chaincode only makes and closes earmarks called
+// from here.
+type DvP struct {
+	ID             string `json:"ID"`
+	AssetChaincode string `json:"assetChaincode"`
+	AssetID        string `json:"assetID"`
+	Quantity       int    `json:"quantity"`
+	Seller         string `json:"seller"`
+	Buyer          string `json:"buyer"`
+	Price          int    `json:"price"`
+	Status         string `json:"status"`
+	Date           string `json:"date"`
+}
+
+// InstructDvP earmarks quantity of assetID held by the seller in the asset chaincode for the buyer,
+// to be delivered when the buyer pays price.
+func (s *RegulatoryContract) InstructDvP(ctx contractapi.TransactionContextInterface, dvpID string, assetID string, quantity int, seller string, buyer string, price int) error {
+	if !isBankOperator(ctx, seller) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", seller)
+	}
+	if quantity <= 0 || price <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "quantity and price must be positive")
+	}
+	if seller == buyer {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "a bank cannot deliver an asset to itself")
+	}
+	existing, err := s.readDvP(ctx, dvpID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the DvP %s already exists", dvpID)
+	}
+	sellerAccount, err := s.ReadAccount(ctx, seller)
+	if err != nil {
+		return err
+	}
+	buyerAccount, err := s.ReadAccount(ctx, buyer)
+	if err != nil {
+		return err
+	}
+	err = s.screenParties(ctx, seller, sellerAccount.Name, buyer, buyerAccount.Name)
+	if err != nil {
+		return err
+	}
+
+	params := []string{"EarmarkAsset", dvpID, assetID, seller, buyer, strconv.Itoa(quantity)}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(assetChaincode, queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+
+	d := DvP{
+		ID:             dvpID,
+		AssetChaincode: assetChaincode,
+		AssetID:        assetID,
+		Quantity:       quantity,
+		Seller:         seller,
+		Buyer:          buyer,
+		Price:          price,
+	}
+	return s.moveDvP(ctx, &d, dvpInstructed)
+}
+
+// SettleDvP pays the price of an instructed DvP from the buyer to the seller and
+// delivers the earmarked asset to the buyer.
+func (s *RegulatoryContract) SettleDvP(ctx contractapi.TransactionContextInterface, dvpID string) error {
+	d, err := s.ReadDvP(ctx, dvpID)
+	if err != nil {
+		return err
+	}
+	if !isBankOperator(ctx, d.Buyer) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", d.Buyer)
+	}
+	if d.Status != dvpInstructed {
+		return cbdcerr.New(cbdcerr.Rejected, "the DvP %s is already %s", dvpID, d.Status)
+	}
+	buyer, err := s.ReadAccount(ctx, d.Buyer)
+	if err != nil {
+		return err
+	}
+	seller, err := s.ReadAccount(ctx, d.Seller)
+	if err != nil {
+		return err
+	}
+	err = s.screenParties(ctx, d.Buyer, buyer.Name, d.Seller, seller.Name)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, d.Buyer, d.Seller, d.Price)
+	if err != nil {
+		return err
+	}
+
+	change := buyer.Balance - d.Price
+	if change < 0 {
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, buyer, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", d.Buyer)
+		}
+		change = 0
+	}
+	buyer.Balance = change
+	seller.Balance = seller.Balance + d.Price
+
+	// 증권 인도와 대금 지급이 같은 트랜잭션에서 커밋됨
+	params := []string{"ReleaseEarmark", dvpID}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(assetChaincode, queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+
+	err = s.putAccount(ctx, buyer)
+	if err != nil {
+		return err
+	}
+	err = s.putAccount(ctx, seller)
+	if err != nil {
+		return err
+	}
+	s.TransferHistory(ctx, d.Seller, d.Buyer, strconv.Itoa(d.Price))
+	return s.moveDvP(ctx, d, dvpSettled)
+}
+
+// CancelDvP returns the earmarked asset of an unsettled DvP to the seller.
+func (s *RegulatoryContract) CancelDvP(ctx contractapi.TransactionContextInterface, dvpID string) error {
+	d, err := s.ReadDvP(ctx, dvpID)
+	if err != nil {
+		return err
+	}
+	if !isBankOperator(ctx, d.Seller) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", d.Seller)
+	}
+	if d.Status != dvpInstructed {
+		return cbdcerr.New(cbdcerr.Rejected, "the DvP %s is already %s", dvpID, d.Status)
+	}
+
+	params := []string{"CancelEarmark", dvpID}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode(assetChaincode, queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+	return s.moveDvP(ctx, d, dvpCancelled)
+}
+
+func (s *RegulatoryContract) ReadDvP(ctx contractapi.TransactionContextInterface, dvpID string) (*DvP, error) {
+	d, err := s.readDvP(ctx, dvpID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the DvP %s does not exist", dvpID)
+	}
+	return d, nil
+}
+
+func (s *RegulatoryContract) readDvP(ctx contractapi.TransactionContextInterface, dvpID string) (*DvP, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(dvpType, []string{dvpID})
+	if err != nil {
+		return nil, err
+	}
+	dvpJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if dvpJSON == nil {
+		return nil, nil
+	}
+	var d DvP
+	err = json.Unmarshal(dvpJSON, &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// moveDvP sets the status of the DvP, stores it and emits it as an event.
+func (s *RegulatoryContract) moveDvP(ctx contractapi.TransactionContextInterface, d *DvP, status string) error {
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	d.Status = status
+	d.Date = date
+
+	dvpJSON, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(dvpType, []string{d.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, dvpJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+	return emitEvent(ctx, "DvP", dvpJSON)
+}
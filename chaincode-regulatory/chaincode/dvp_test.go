@@ -0,0 +1,110 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// assetLedger stands in for the asset chaincode, recording the calls made to it.
+type assetLedger struct {
+	calls [][]string
+	fail  string
+}
+
+func (a *assetLedger) invoke(args []string) peer.Response {
+	if args[0] == a.fail {
+		return shim.Error("the earmark cannot be changed")
+	}
+	a.calls = append(a.calls, args)
+	return shim.Success(nil)
+}
+
+func newDvPLedger(t *testing.T) (*ledger, *assetLedger) {
+	l := newLedger(t, map[string]int{"Bank1": 500, "Bank2": 500})
+	asset := &assetLedger{}
+	l.peer("assetchaincode", "regulatory-channel", asset.invoke)
+	return l, asset
+}
+
+func instruct(l *ledger, dvpID string, seller string, buyer string, price int) error {
+	s := chaincode.RegulatoryContract{}
+	return l.tx(operator(seller), func(ctx contractapi.TransactionContextInterface) error {
+		return s.InstructDvP(ctx, dvpID, "bond1", 10, seller, buyer, price)
+	})
+}
+
+func TestDvPSettle(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	l, asset := newDvPLedger(t)
+	require.NoError(t, instruct(l, "dvp1", "Bank1", "Bank2", 300))
+	require.Equal(t, [][]string{{"EarmarkAsset", "dvp1", "bond1", "Bank1", "Bank2", "10"}}, asset.calls)
+
+	err := l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleDvP(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "the client does not operate Bank2")
+
+	require.NoError(t, l.tx(operator("Bank2"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleDvP(ctx, "dvp1") }))
+	require.Equal(t, []string{"ReleaseEarmark", "dvp1"}, asset.calls[1])
+	require.Equal(t, 800, l.balance("Bank1"))
+	require.Equal(t, 200, l.balance("Bank2"))
+
+	err = l.tx(operator("Bank2"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleDvP(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Rejected, "the DvP dvp1 is already settled")
+	err = l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.CancelDvP(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Rejected, "the DvP dvp1 is already settled")
+	require.Len(t, asset.calls, 2)
+}
+
+func TestDvPCancel(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	l, asset := newDvPLedger(t)
+	require.NoError(t, instruct(l, "dvp1", "Bank1", "Bank2", 300))
+
+	err := l.tx(operator("Bank2"), func(ctx contractapi.TransactionContextInterface) error { return s.CancelDvP(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "the client does not operate Bank1")
+
+	require.NoError(t, l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.CancelDvP(ctx, "dvp1") }))
+	require.Equal(t, []string{"CancelEarmark", "dvp1"}, asset.calls[1])
+
+	err = l.tx(operator("Bank2"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleDvP(ctx, "dvp1") })
+	requireCode(t, err, cbdcerr.Rejected, "the DvP dvp1 is already cancelled")
+	require.Equal(t, 500, l.balance("Bank1"))
+	require.Equal(t, 500, l.balance("Bank2"))
+}
+
+func TestDvPRejects(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	for _, tc := range []struct {
+		name    string
+		seller  string
+		buyer   string
+		price   int
+		fail    string
+		code    cbdcerr.Code
+		message string
+	}{
+		{"seller is the buyer", "Bank1", "Bank1", 300, "", cbdcerr.InvalidArgument, "a bank cannot deliver an asset to itself"},
+		{"price not positive", "Bank1", "Bank2", 0, "", cbdcerr.InvalidArgument, "quantity and price must be positive"},
+		{"unknown buyer", "Bank1", "Bank9", 300, "", cbdcerr.NotFound, "the asset Bank9 does not exist"},
+		{"asset not earmarked", "Bank1", "Bank2", 300, "EarmarkAsset", cbdcerr.Rejected, "the earmark cannot be changed"},
+		{"buyer cannot pay", "Bank1", "Bank2", 600, "", cbdcerr.InsufficientFunds, "Lack of balance Bank2's Account"},
+		{"asset not delivered", "Bank1", "Bank2", 300, "ReleaseEarmark", cbdcerr.Rejected, "the earmark cannot be changed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, asset := newDvPLedger(t)
+			asset.fail = tc.fail
+			err := instruct(l, "dvp1", tc.seller, tc.buyer, tc.price)
+			if err == nil {
+				err = l.tx(operator(tc.buyer), func(ctx contractapi.TransactionContextInterface) error { return s.SettleDvP(ctx, "dvp1") })
+			}
+			requireCode(t, err, tc.code, tc.message)
+			require.Equal(t, 500, l.balance("Bank1"))
+			require.Equal(t, 500, l.balance("Bank2"))
+		})
+	}
+}
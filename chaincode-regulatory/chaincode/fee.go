@@ -0,0 +1,273 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	feeScheduleType = "feeSchedule"
+	feeTierType     = "feeTier"
+	feeWaiverKey    = "feeWaiver"
+
+	// Transaction types a fee schedule applies to.
+	feeInterbank  = "interbank"  // TransferBalanceBank
+	feeBankToUser = "bankToUser" // UpdateSendBalance
+	feeMerchant   = "merchant"   // TransferBalanceUser to a merchant
+	feeP2P        = "p2p"        // TransferBalanceUser between retail users
+
+	feeFlat    = "flat"
+	feePercent = "percent"
+
+	// defaultFeeTier is the tier of payers that were not given one.
+	defaultFeeTier = "standard"
+
+	// CentralBankFeeAccount is the account fees payable to the central bank are credited to.
+	CentralBankFeeAccount = "CentralBankFees"
+)
+
+// FeeSchedule is the fee charged to payers of Tier on transactions of TxType.
+// A flat fee is Value units; a percentage fee is Value basis points of the amount,
+// rounded down. The fee is credited to Payee, a bank or CentralBankFeeAccount.
+type FeeSchedule struct {
+	TxType string `json:"txType"`
+	Tier   string `json:"tier"`
+	Kind   string `json:"kind"`
+	Value  int    `json:"value"`
+	Payee  string `json:"payee"`
+}
+
+// FeeQuote is the fee of one transaction.
+type FeeQuote struct {
+	TxType string `json:"txType"`
+	Payer  string `json:"payer"`
+	Tier   string `json:"tier"`
+	Amount int    `json:"amount"`
+	Fee    int    `json:"fee"`
+	Payee  string `json:"payee,omitempty"`
+	Waived bool   `json:"waived,omitempty"`
+}
+
+// feeWaiver holds which fees are waived. Retail P2P payments are free
+// unless the regulator turns their waiver off.
+type feeWaiver struct {
+	ChargeRetailP2P bool `json:"chargeRetailP2P"`
+}
+
+// SetFeeSchedule sets the fee of txType (interbank, bankToUser, merchant or p2p) for payers of tier.
+// kind is flat or percent; value is units or basis points. Only the regulator can set fees.
+func (s *RegulatoryContract) SetFeeSchedule(ctx contractapi.TransactionContextInterface, txType string, tier string, kind string, value string, payee string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set fee schedules")
+	}
+	switch txType {
+	case feeInterbank, feeBankToUser, feeMerchant, feeP2P:
+	default:
+		return cbdcerr.New(cbdcerr.InvalidArgument, "unknown transaction type %s", txType)
+	}
+	if kind != feeFlat && kind != feePercent {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "fee kind must be %s or %s", feeFlat, feePercent)
+	}
+	valueNum, e := strconv.Atoi(value)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid fee value %q", value)
+	}
+	if valueNum < 0 || (kind == feePercent && valueNum > 10000) {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid fee value %d", valueNum)
+	}
+	if payee != CentralBankFeeAccount {
+		if _, err := s.ReadAccount(ctx, payee); err != nil {
+			return err
+		}
+	}
+	if tier == "" {
+		tier = defaultFeeTier
+	}
+
+	schedule := FeeSchedule{TxType: txType, Tier: tier, Kind: kind, Value: valueNum, Payee: payee}
+	key, err := ctx.GetStub().CreateCompositeKey(feeScheduleType, []string{txType, tier})
+	if err != nil {
+		return err
+	}
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, scheduleJSON)
+}
+
+// RemoveFeeSchedule stops charging fees on txType for payers of tier.
+func (s *RegulatoryContract) RemoveFeeSchedule(ctx contractapi.TransactionContextInterface, txType string, tier string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can remove fee schedules")
+	}
+	if tier == "" {
+		tier = defaultFeeTier
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(feeScheduleType, []string{txType, tier})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// ReadFeeSchedules returns all fee schedules.
+func (s *RegulatoryContract) ReadFeeSchedules(ctx contractapi.TransactionContextInterface) ([]*FeeSchedule, error) {
+	scheduleJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(feeScheduleType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer scheduleJSON.Close()
+	var schedules []*FeeSchedule
+	for scheduleJSON.HasNext() {
+		queryResponse, err := scheduleJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var schedule FeeSchedule
+		err = json.Unmarshal(queryResponse.Value, &schedule)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+	return schedules, nil
+}
+
+// SetFeeTier puts a bank or user account in a fee tier.
+func (s *RegulatoryContract) SetFeeTier(ctx contractapi.TransactionContextInterface, id string, tier string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set fee tiers")
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(feeTierType, []string{id})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(tier))
+}
+
+// ReadFeeTier returns the fee tier of a bank or user account.
+func (s *RegulatoryContract) ReadFeeTier(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(feeTierType, []string{id})
+	if err != nil {
+		return "", err
+	}
+	tier, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read world state: %v", err)
+	}
+	if tier == nil {
+		return defaultFeeTier, nil
+	}
+	return string(tier), nil
+}
+
+// SetRetailP2PFees turns fees on retail P2P payments on or off. They are waived by default.
+func (s *RegulatoryContract) SetRetailP2PFees(ctx contractapi.TransactionContextInterface, charge bool) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can waive fees")
+	}
+	waiverJSON, err := json.Marshal(feeWaiver{ChargeRetailP2P: charge})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(feeWaiverKey, waiverJSON)
+}
+
+// QuoteFee returns the fee payer would pay on a transaction of txType of amount.
+func (s *RegulatoryContract) QuoteFee(ctx contractapi.TransactionContextInterface, txType string, payer string, amount string) (*FeeQuote, error) {
+	amountNum, e := strconv.Atoi(amount)
+	if e != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid amount %q", amount)
+	}
+	return s.quoteFee(ctx, txType, payer, amountNum)
+}
+
+func (s *RegulatoryContract) quoteFee(ctx contractapi.TransactionContextInterface, txType string, payer string, amount int) (*FeeQuote, error) {
+	tier, err := s.ReadFeeTier(ctx, payer)
+	if err != nil {
+		return nil, err
+	}
+	quote := FeeQuote{TxType: txType, Payer: payer, Tier: tier, Amount: amount}
+	if txType == feeP2P {
+		var waiver feeWaiver
+		waiverJSON, err := ctx.GetStub().GetState(feeWaiverKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read world state: %v", err)
+		}
+		if waiverJSON != nil {
+			err = json.Unmarshal(waiverJSON, &waiver)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !waiver.ChargeRetailP2P {
+			quote.Waived = true
+			return &quote, nil
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(feeScheduleType, []string{txType, tier})
+	if err != nil {
+		return nil, err
+	}
+	scheduleJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if scheduleJSON == nil {
+		return &quote, nil
+	}
+	var schedule FeeSchedule
+	err = json.Unmarshal(scheduleJSON, &schedule)
+	if err != nil {
+		return nil, err
+	}
+	quote.Payee = schedule.Payee
+	if schedule.Kind == feePercent {
+		quote.Fee = amount * schedule.Value / 10000
+	} else {
+		quote.Fee = schedule.Value
+	}
+	return &quote, nil
+}
+
+// creditFee adds the fee of quote to its payee. accounts holds the accounts the caller
+// has changed and will write itself; a payee among them is credited there instead.
+func (s *RegulatoryContract) creditFee(ctx contractapi.TransactionContextInterface, quote *FeeQuote, accounts map[string]*Account) error {
+	if quote.Fee == 0 {
+		return nil
+	}
+	if account, ok := accounts[quote.Payee]; ok {
+		account.Balance = account.Balance + quote.Fee
+		return nil
+	}
+	payee, err := s.readPayee(ctx, quote.Payee)
+	if err != nil {
+		return err
+	}
+	payee.Balance = payee.Balance + quote.Fee
+	payeeJSON, err := json.Marshal(payee)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(payee.ID, payeeJSON)
+}
+
+// readPayee reads the account of a fee payee. The central bank fee account is
+// created by the first fee paid to it.
+func (s *RegulatoryContract) readPayee(ctx contractapi.TransactionContextInterface, id string) (*Account, error) {
+	payee, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		if id != CentralBankFeeAccount || cbdcerr.CodeOf(err) != cbdcerr.NotFound {
+			return nil, err
+		}
+		// 중앙은행 수수료 계좌는 첫 수수료 입금 시 생성
+		payee = &Account{ID: CentralBankFeeAccount, Name: "Central Bank Fees"}
+	}
+	return payee, nil
+}
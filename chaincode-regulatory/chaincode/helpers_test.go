@@ -0,0 +1,220 @@
+package chaincode_test
+
+import (
+	"container/list"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// client is a client of mspID enrolled with the CA attributes attrs.
+type client struct {
+	mspID string
+	attrs map[string]string
+}
+
+// owner is the client owning the user account id.
+func owner(id string) client {
+	return client{mspID: "consumerOrg", attrs: map[string]string{"userID": id}}
+}
+
+// operator is a client operating bank.
+func operator(bank string) client {
+	return client{mspID: "commercialbankOrg", attrs: map[string]string{"bankID": bank}}
+}
+
+var (
+	regulator = client{mspID: "centralbankOrg"}
+	scheduler = client{mspID: "commercialbankOrg", attrs: map[string]string{"scheduler": "true"}}
+)
+
+func (c client) GetID() (string, error)    { return fmt.Sprint(c.mspID, c.attrs), nil }
+func (c client) GetMSPID() (string, error) { return c.mspID, nil }
+func (c client) GetAttributeValue(name string) (string, bool, error) {
+	value, found := c.attrs[name]
+	return value, found, nil
+}
+func (c client) AssertAttributeValue(name string, value string) error {
+	if c.attrs[name] != value {
+		return fmt.Errorf("attribute %s is not %s", name, value)
+	}
+	return nil
+}
+func (c client) GetX509Certificate() (*x509.Certificate, error) { return nil, nil }
+
+// peerChaincode stands in for a chaincode called by regulatorychaincode, answering each call
+// from its arguments.
+type peerChaincode func(args []string) peer.Response
+
+func (f peerChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response { return shim.Success(nil) }
+func (f peerChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	return f(stub.GetStringArgs())
+}
+
+// answers is a peerChaincode answering each function with a fixed payload.
+func answers(payloads map[string]string) peerChaincode {
+	return func(args []string) peer.Response {
+		payload, ok := payloads[args[0]]
+		if !ok {
+			return shim.Error("unexpected call to " + args[0])
+		}
+		return shim.Success([]byte(payload))
+	}
+}
+
+// ledgerStub is the stub of one transaction, carrying the transient map.
+type ledgerStub struct {
+	*shimtest.MockStub
+	l *ledger
+}
+
+func (s ledgerStub) GetTransient() (map[string][]byte, error) { return s.l.transient, nil }
+
+func (s ledgerStub) SetEvent(name string, payload []byte) error {
+	s.l.events[s.GetTxID()] = &peer.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
+// ledger is the world state of regulatorychaincode, changed one transaction at a time.
+type ledger struct {
+	t         *testing.T
+	stub      *shimtest.MockStub
+	n         int
+	now       time.Time
+	transient map[string][]byte
+	events    map[string]*peer.ChaincodeEvent
+}
+
+// newLedger opens the bank accounts in balances.
+func newLedger(t *testing.T, balances map[string]int) *ledger {
+	l := &ledger{
+		t:      t,
+		stub:   shimtest.NewMockStub("regulatorychaincode", nil),
+		now:    time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		events: make(map[string]*peer.ChaincodeEvent),
+	}
+	for id, balance := range balances {
+		l.put(id, chaincode.Account{ID: id, Name: id, Balance: balance})
+	}
+	return l
+}
+
+// peer routes the calls to the chaincode name on channel to cc.
+func (l *ledger) peer(name string, channel string, cc peerChaincode) {
+	l.stub.MockPeerChaincode(name, shimtest.NewMockStub(name, cc), channel)
+}
+
+// txChaincode runs one transaction function as a chaincode.
+type txChaincode func(stub shim.ChaincodeStubInterface) error
+
+func (f txChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response { return shim.Success(nil) }
+func (f txChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	if err := f(stub); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+// tx runs fn as a transaction of c, behind the WatchList wrapper. Like a peer, it discards
+// the writes and the event of a transaction that fails; a refused transaction is committed
+// and its refusal returned.
+func (l *ledger) tx(c client, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	l.n++
+	txID := fmt.Sprintf("%s/tx%03d", l.t.Name(), l.n)
+	l.stub.MockTransactionStart(txID)
+	defer l.stub.MockTransactionEnd(txID)
+	ts, err := ptypes.TimestampProto(l.now)
+	require.NoError(l.t, err)
+	l.stub.TxTimestamp = ts
+
+	state, keys := l.snapshot()
+	cc := chaincode.WatchList(txChaincode(func(stub shim.ChaincodeStubInterface) error {
+		ctx := &contractapi.TransactionContext{}
+		ctx.SetStub(stub)
+		ctx.SetClientIdentity(c)
+		err = fn(ctx)
+		return err
+	}))
+	response := cc.Invoke(ledgerStub{MockStub: l.stub, l: l})
+	switch {
+	case response.Status == cbdcerr.Refused:
+		return cbdcerr.Parse(response.Message)
+	case response.Status >= shim.ERRORTHRESHOLD:
+		l.stub.State, l.stub.Keys = state, keys
+		delete(l.events, txID)
+		if err == nil {
+			err = cbdcerr.Parse(response.Message)
+		}
+		return err
+	}
+	return nil
+}
+
+// snapshot copies the world state.
+func (l *ledger) snapshot() (map[string][]byte, *list.List) {
+	state := make(map[string][]byte, len(l.stub.State))
+	for key, value := range l.stub.State {
+		state[key] = value
+	}
+	keys := list.New()
+	for e := l.stub.Keys.Front(); e != nil; e = e.Next() {
+		keys.PushBack(e.Value)
+	}
+	return state, keys
+}
+
+// lastEvent returns the event set by the last transaction.
+func (l *ledger) lastEvent() *peer.ChaincodeEvent {
+	return l.events[fmt.Sprintf("%s/tx%03d", l.t.Name(), l.n)]
+}
+
+// put stores v as JSON under key.
+func (l *ledger) put(key string, v interface{}) {
+	valueJSON, err := json.Marshal(v)
+	require.NoError(l.t, err)
+	require.NoError(l.t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		return ctx.GetStub().PutState(key, valueJSON)
+	}))
+}
+
+// get reads the JSON stored under key into v.
+func (l *ledger) get(key string, v interface{}) {
+	valueJSON, ok := l.stub.State[key]
+	require.True(l.t, ok, "%s is not in the world state", key)
+	require.NoError(l.t, json.Unmarshal(valueJSON, v))
+}
+
+// record reads the record objectType/id into v.
+func (l *ledger) record(objectType string, id string, v interface{}) {
+	key, err := l.stub.CreateCompositeKey(objectType, []string{id})
+	require.NoError(l.t, err)
+	l.get(key, v)
+}
+
+func (l *ledger) account(id string) *chaincode.Account {
+	var account chaincode.Account
+	l.get(id, &account)
+	return &account
+}
+
+func (l *ledger) balance(id string) int {
+	return l.account(id).Balance
+}
+
+// requireCode checks that err is the cbdcerr error of code with message.
+func requireCode(t *testing.T, err error, code cbdcerr.Code, message string) {
+	t.Helper()
+	require.Error(t, err)
+	require.Equal(t, &cbdcerr.Error{Code: code, Message: message}, cbdcerr.From(err))
+}
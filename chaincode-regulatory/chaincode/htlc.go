@@ -0,0 +1,254 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	htlcType = "htlc"
+
+	htlcLocked   = "locked"
+	htlcClaimed  = "claimed"
+	htlcRefunded = "refunded"
+)
+
+// HTLC is an amount locked from a bank account under a hash lock, for atomic settlement
+// against an asset on another ledger. The receiver gets it with the preimage of HashLock
+// before Timeout (unix seconds); after Timeout it goes back to the sender.
+// While locked it is moved from the sender's Balance to Locked, so it cannot be spent.
+type HTLC struct {
+	ID       string `json:"ID"`
+	Sender   string `json:"sender"`
+	Receiver string `json:"receiver"`
+	Amount   int    `json:"amount"`
+	HashLock string `json:"hashLock"`
+	Preimage string `json:"preimage,omitempty"`
+	Timeout  int64  `json:"timeout"`
+	Status   string `json:"status"`
+	Date     string `json:"date"`
+}
+
+// LockHTLC locks amount from the bank id for rec under hashLock, the hex encoded SHA-256
+// of a preimage, until timeout.
+func (s *RegulatoryContract) LockHTLC(ctx contractapi.TransactionContextInterface, htlcID string, id string, rec string, amount int, hashLock string, timeout int64) error {
+	if !isBankOperator(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the client does not operate %s", id)
+	}
+	if _, err := hex.DecodeString(hashLock); err != nil || len(hashLock) != sha256.Size*2 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "hash lock must be a hex encoded SHA-256 digest")
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	if rec == id {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "an HTLC cannot pay its own sender")
+	}
+	existing, err := s.readHTLC(ctx, htlcID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the HTLC %s already exists", htlcID)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if timeout <= ts.Seconds {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "timeout must be in the future")
+	}
+
+	sender, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, rec)
+	if err != nil {
+		return err
+	}
+	err = s.screenParties(ctx, id, sender.Name, rec, receiver.Name)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, id, rec, amount)
+	if err != nil {
+		return err
+	}
+
+	change := sender.Balance - amount
+	if change < 0 {
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, sender, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+		}
+		change = 0
+	}
+	sender.Balance = change
+	sender.Locked = sender.Locked + amount
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	htlc := HTLC{
+		ID:       htlcID,
+		Sender:   id,
+		Receiver: rec,
+		Amount:   amount,
+		HashLock: hashLock,
+		Timeout:  timeout,
+	}
+	return s.moveHTLC(ctx, &htlc, htlcLocked)
+}
+
+// ClaimHTLC pays a locked HTLC to its receiver given the preimage of its hash lock.
+// Anyone holding the preimage can submit it; the preimage is published in the HTLC event
+// for the counterparty on the other ledger.
+func (s *RegulatoryContract) ClaimHTLC(ctx contractapi.TransactionContextInterface, htlcID string, preimage string) error {
+	htlc, err := s.ReadHTLC(ctx, htlcID)
+	if err != nil {
+		return err
+	}
+	if htlc.Status != htlcLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s is already %s", htlcID, htlc.Status)
+	}
+	digest := sha256.Sum256([]byte(preimage))
+	if hex.EncodeToString(digest[:]) != htlc.HashLock {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid preimage for the HTLC %s", htlcID)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if ts.Seconds >= htlc.Timeout {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s has timed out", htlcID)
+	}
+
+	sender, err := s.ReadAccount(ctx, htlc.Sender)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, htlc.Receiver)
+	if err != nil {
+		return err
+	}
+	sender.Locked = sender.Locked - htlc.Amount
+	receiver.Balance = receiver.Balance + htlc.Amount
+	htlc.Preimage = preimage
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	err = s.putAccount(ctx, receiver)
+	if err != nil {
+		return err
+	}
+	s.TransferHistory(ctx, htlc.Receiver, htlc.Sender, strconv.Itoa(htlc.Amount))
+	return s.moveHTLC(ctx, htlc, htlcClaimed)
+}
+
+// RefundHTLC returns a locked HTLC to its sender once it has timed out.
+func (s *RegulatoryContract) RefundHTLC(ctx contractapi.TransactionContextInterface, htlcID string) error {
+	htlc, err := s.ReadHTLC(ctx, htlcID)
+	if err != nil {
+		return err
+	}
+	if htlc.Status != htlcLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s is already %s", htlcID, htlc.Status)
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if ts.Seconds < htlc.Timeout {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s has not timed out yet", htlcID)
+	}
+
+	sender, err := s.ReadAccount(ctx, htlc.Sender)
+	if err != nil {
+		return err
+	}
+	sender.Locked = sender.Locked - htlc.Amount
+	sender.Balance = sender.Balance + htlc.Amount
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	return s.moveHTLC(ctx, htlc, htlcRefunded)
+}
+
+func (s *RegulatoryContract) ReadHTLC(ctx contractapi.TransactionContextInterface, htlcID string) (*HTLC, error) {
+	htlc, err := s.readHTLC(ctx, htlcID)
+	if err != nil {
+		return nil, err
+	}
+	if htlc == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the HTLC %s does not exist", htlcID)
+	}
+	return htlc, nil
+}
+
+func (s *RegulatoryContract) readHTLC(ctx contractapi.TransactionContextInterface, htlcID string) (*HTLC, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(htlcType, []string{htlcID})
+	if err != nil {
+		return nil, err
+	}
+	htlcJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if htlcJSON == nil {
+		return nil, nil
+	}
+	var htlc HTLC
+	err = json.Unmarshal(htlcJSON, &htlc)
+	if err != nil {
+		return nil, err
+	}
+	return &htlc, nil
+}
+
+// moveHTLC sets the status of the HTLC, stores it and emits it as an event.
+func (s *RegulatoryContract) moveHTLC(ctx contractapi.TransactionContextInterface, htlc *HTLC, status string) error {
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	htlc.Status = status
+	htlc.Date = date
+
+	htlcJSON, err := json.Marshal(htlc)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(htlcType, []string{htlc.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, htlcJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+	return emitEvent(ctx, "HTLC", htlcJSON)
+}
+
+func (s *RegulatoryContract) putAccount(ctx contractapi.TransactionContextInterface, account *Account) error {
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(account.ID, accountJSON)
+}
@@ -0,0 +1,324 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	overnightLoanType = "overnightLoan"
+	creditBookingType = "creditBooking"
+)
+
+// overnightLoan is created when intraday credit is still drawn at end of day.
+type overnightLoan struct {
+	ID        string `json:"ID"`
+	BankID    string `json:"bankID"`
+	Principal int    `json:"principal"`
+	Interest  int    `json:"interest"`
+	Rate      int    `json:"rate"`
+	Date      string `json:"date"`
+	Repaid    bool   `json:"repaid"`
+}
+
+// creditBooking is a movement of central bank credit on a bank account in one
+// transaction. The drawn credit is new CBDC and repayments retire it, so the central
+// bank chaincode reads each booking with BookCredit and applies it once to its total
+// balance; InvokeChaincode on another channel only reads, so it is not applied from here.
+type creditBooking struct {
+	ID       string `json:"ID"`
+	BankID   string `json:"bankID"`
+	Drawn    int    `json:"drawn"`
+	Repaid   int    `json:"repaid"`
+	Interest int    `json:"interest"`
+	Date     string `json:"date"`
+}
+
+type creditEvent struct {
+	BankID string `json:"bankID"`
+	Amount int    `json:"amount"`
+	Drawn  int    `json:"drawn"`
+}
+
+// txDate returns the transaction timestamp in the format used by history records.
+func txDate(ctx contractapi.TransactionContextInterface) (string, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format("2006-01-02 15:04"), nil
+}
+
+// grantedLine is the credit line granted by the central bank chaincode.
+type grantedLine struct {
+	BankID string `json:"bankID"`
+	Limit  int    `json:"limit"`
+	Rate   int    `json:"rate"`
+}
+
+// SetCreditLine applies to a bank account the credit line the central bank granted it
+// with GrantCreditLine on the central bank channel. Only the regulator can set it.
+func (s *RegulatoryContract) SetCreditLine(ctx contractapi.TransactionContextInterface, id string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set credit lines")
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// 중앙은행 채널의 승인 내역은 조회만 가능
+	params := []string{"ReadCreditLine", id}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+
+	response := ctx.GetStub().InvokeChaincode("mychaincode", queryArgs, "centralbank-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+	var line grantedLine
+	err = json.Unmarshal(response.Payload, &line)
+	if err != nil {
+		return err
+	}
+	limitNum := line.Limit
+	rateNum := line.Rate
+	if limitNum < account.CreditDrawn+account.OvernightLoan {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "credit limit %d is below the outstanding credit of %s", limitNum, id)
+	}
+
+	account.CreditLimit = limitNum
+	account.CreditRate = rateNum
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// drawIntradayCredit covers a shortfall from the bank's credit line and books it.
+// It returns false when the remaining line is not enough.
+func (s *RegulatoryContract) drawIntradayCredit(ctx contractapi.TransactionContextInterface, account *Account, amount int) (bool, error) {
+	available := account.CreditLimit - account.CreditDrawn - account.OvernightLoan
+	if amount > available {
+		return false, nil
+	}
+	account.CreditDrawn = account.CreditDrawn + amount
+
+	// 같은 트랜잭션의 이체 기록과 겹치지 않도록 이벤트로 남김
+	eventJSON, err := json.Marshal(creditEvent{BankID: account.ID, Amount: amount, Drawn: account.CreditDrawn})
+	if err != nil {
+		return false, err
+	}
+	err = emitEvent(ctx, "IntradayCreditDrawn", eventJSON)
+	if err != nil {
+		return false, err
+	}
+	err = s.bookCredit(ctx, &creditBooking{BankID: account.ID, Drawn: amount})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// bookCredit stores the credit booking of the bank in this transaction. A bank draws
+// or repays at most once per transaction, so the booking is keyed by bank and tx ID.
+func (s *RegulatoryContract) bookCredit(ctx contractapi.TransactionContextInterface, booking *creditBooking) error {
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	booking.ID = booking.BankID + "-" + ctx.GetStub().GetTxID()
+	booking.Date = date
+	key, err := ctx.GetStub().CreateCompositeKey(creditBookingType, []string{booking.ID})
+	if err != nil {
+		return err
+	}
+	bookingJSON, err := json.Marshal(booking)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, bookingJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put to world state. %v", err)
+	}
+	return emitEvent(ctx, "CreditBooked", bookingJSON)
+}
+
+// ReadCreditBooking returns a credit booking.
+func (s *RegulatoryContract) ReadCreditBooking(ctx contractapi.TransactionContextInterface, bookingID string) (*creditBooking, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(creditBookingType, []string{bookingID})
+	if err != nil {
+		return nil, err
+	}
+	bookingJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if bookingJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the credit booking %s does not exist", bookingID)
+	}
+	var booking creditBooking
+	err = json.Unmarshal(bookingJSON, &booking)
+	if err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// canSettleCredit reports whether the client may run the end of day credit jobs of
+// bank id: the regulator, the scheduler or an operator of the bank.
+func canSettleCredit(ctx contractapi.TransactionContextInterface, id string) bool {
+	return isRegulator(ctx) || isScheduler(ctx) || isBankOperator(ctx, id)
+}
+
+// SettleIntradayCredit repays the drawn intraday credit at end of day.
+// Whatever the balance cannot cover is converted to an overnight loan with interest.
+// The regulator, the scheduler or an operator of the bank can run it.
+func (s *RegulatoryContract) SettleIntradayCredit(ctx contractapi.TransactionContextInterface, id string) error {
+	if !canSettleCredit(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator, the scheduler or %s can settle its intraday credit", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if account.CreditDrawn == 0 {
+		return nil
+	}
+
+	repay := account.CreditDrawn
+	if account.Balance < repay {
+		repay = account.Balance
+	}
+	account.Balance = account.Balance - repay
+	account.CreditDrawn = account.CreditDrawn - repay
+	if repay > 0 {
+		s.TransferHistory(ctx, "Central Bank Credit", id, strconv.Itoa(repay))
+		err = s.bookCredit(ctx, &creditBooking{BankID: id, Repaid: repay})
+		if err != nil {
+			return err
+		}
+	}
+
+	if account.CreditDrawn > 0 {
+		date, err := txDate(ctx)
+		if err != nil {
+			return err
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(overnightLoanType, []string{id, ctx.GetStub().GetTxID()})
+		if err != nil {
+			return err
+		}
+		loan := overnightLoan{
+			ID:        key,
+			BankID:    id,
+			Principal: account.CreditDrawn,
+			Interest:  account.CreditDrawn * account.CreditRate / 10000,
+			Rate:      account.CreditRate,
+			Date:      date,
+		}
+		loanJSON, err := json.Marshal(loan)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(key, loanJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put to world state. %v", err)
+		}
+		account.OvernightLoan = account.OvernightLoan + loan.Principal + loan.Interest
+		account.CreditDrawn = 0
+	}
+
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// RepayOvernightLoans pays back every open overnight loan of the bank with interest.
+// The regulator, the scheduler or an operator of the bank can run it.
+func (s *RegulatoryContract) RepayOvernightLoans(ctx contractapi.TransactionContextInterface, id string) error {
+	if !canSettleCredit(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator, the scheduler or %s can repay its overnight loans", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	loans, err := s.ReadOvernightLoans(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	booking := creditBooking{BankID: id}
+	for _, loan := range loans {
+		if loan.Repaid {
+			continue
+		}
+		due := loan.Principal + loan.Interest
+		if account.Balance < due {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+		}
+		account.Balance = account.Balance - due
+		account.OvernightLoan = account.OvernightLoan - due
+		loan.Repaid = true
+
+		loanJSON, err := json.Marshal(loan)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(loan.ID, loanJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put to world state. %v", err)
+		}
+		booking.Repaid = booking.Repaid + loan.Principal
+		booking.Interest = booking.Interest + loan.Interest
+	}
+	if booking.Repaid+booking.Interest == 0 {
+		return nil
+	}
+	s.TransferHistory(ctx, "Central Bank Credit", id, strconv.Itoa(booking.Repaid+booking.Interest))
+	err = s.bookCredit(ctx, &booking)
+	if err != nil {
+		return err
+	}
+
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// ReadOvernightLoans returns all overnight loans of a bank.
+func (s *RegulatoryContract) ReadOvernightLoans(ctx contractapi.TransactionContextInterface, id string) ([]*overnightLoan, error) {
+	loanJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(overnightLoanType, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer loanJSON.Close()
+	var loans []*overnightLoan
+	for loanJSON.HasNext() {
+		queryResponse, err := loanJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var loan overnightLoan
+		err = json.Unmarshal(queryResponse.Value, &loan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, &loan)
+	}
+	return loans, nil
+}
@@ -0,0 +1,80 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+type creditBooking struct {
+	BankID   string `json:"bankID"`
+	Drawn    int    `json:"drawn"`
+	Repaid   int    `json:"repaid"`
+	Interest int    `json:"interest"`
+}
+
+// creditBookings returns the credit bookings committed so far, in tx order.
+func creditBookings(l *ledger) []creditBooking {
+	var keys []string
+	for key := range l.stub.State {
+		if strings.HasPrefix(key, "\x00creditBooking\x00") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var bookings []creditBooking
+	for _, key := range keys {
+		var booking creditBooking
+		require.NoError(l.t, json.Unmarshal(l.stub.State[key], &booking))
+		bookings = append(bookings, booking)
+	}
+	return bookings
+}
+
+func TestIntradayCredit(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	l := newLedger(t, map[string]int{"Bank2": 500})
+	l.put("Bank1", chaincode.Account{ID: "Bank1", Name: "Bank1", Balance: 100, CreditLimit: 1000, CreditRate: 100})
+	transfer := func(from string, to string, price string) {
+		require.NoError(t, l.tx(operator(from), func(ctx contractapi.TransactionContextInterface) error {
+			return s.TransferBalanceBank(ctx, from, to, price)
+		}))
+	}
+
+	// 잔액 100 을 넘는 200 은 일중 신용으로 인출
+	transfer("Bank1", "Bank2", "300")
+	require.Equal(t, 0, l.balance("Bank1"))
+	require.Equal(t, 200, l.account("Bank1").CreditDrawn)
+	require.Equal(t, []creditBooking{{BankID: "Bank1", Drawn: 200}}, creditBookings(l))
+
+	transfer("Bank2", "Bank1", "50")
+	err := l.tx(operator("Bank2"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleIntradayCredit(ctx, "Bank1") })
+	requireCode(t, err, cbdcerr.Unauthorized, "only the regulator, the scheduler or Bank1 can settle its intraday credit")
+
+	require.NoError(t, l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.SettleIntradayCredit(ctx, "Bank1") }))
+	account := l.account("Bank1")
+	require.Equal(t, 0, account.Balance)
+	require.Equal(t, 0, account.CreditDrawn)
+	require.Equal(t, 151, account.OvernightLoan)
+	require.Equal(t, creditBooking{BankID: "Bank1", Repaid: 50}, creditBookings(l)[1])
+
+	err = l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.RepayOvernightLoans(ctx, "Bank1") })
+	requireCode(t, err, cbdcerr.InsufficientFunds, "Lack of balance Bank1's Account")
+
+	transfer("Bank2", "Bank1", "200")
+	require.NoError(t, l.tx(scheduler, func(ctx contractapi.TransactionContextInterface) error { return s.RepayOvernightLoans(ctx, "Bank1") }))
+	account = l.account("Bank1")
+	require.Equal(t, 49, account.Balance)
+	require.Equal(t, 0, account.OvernightLoan)
+	require.Equal(t, []creditBooking{
+		{BankID: "Bank1", Drawn: 200},
+		{BankID: "Bank1", Repaid: 50},
+		{BankID: "Bank1", Repaid: 150, Interest: 1},
+	}, creditBookings(l))
+}
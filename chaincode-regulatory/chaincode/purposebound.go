@@ -0,0 +1,155 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const taggedIssueType = "taggedIssue"
+
+// taggedIssue is a purpose-bound payment debited from a bank, to be credited to the user
+// by IssueTaggedBalance on the user channel.
+type taggedIssue struct {
+	ID         string   `json:"ID"`
+	BankID     string   `json:"bankID"`
+	UserID     string   `json:"userID"`
+	Amount     int      `json:"amount"`
+	Purpose    string   `json:"purpose"`
+	Categories []string `json:"categories"`
+	Expiry     int64    `json:"expiry"`
+	Date       string   `json:"date"`
+}
+
+// UpdateSendTaggedBalance pays a purpose-bound balance from a bank to a user.
+// The user may only spend it at the given merchant categories until expiry (unix seconds),
+// after which the user chaincode returns what is left to the bank.
+// The bank is debited here and the issue recorded under issueID; the user chaincode
+// credits it with IssueTaggedBalance.
+func (s *RegulatoryContract) UpdateSendTaggedBalance(ctx contractapi.TransactionContextInterface, issueID string, id string, rec string, balance string, purpose string, categories []string, expiry int64) error {
+	if !isBankOperator(ctx, id) && !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only an operator of %s or the regulator can pay from it", id)
+	}
+	existing, err := s.readTaggedIssue(ctx, issueID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the tagged issue %s already exists", issueID)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	balNum, e := strconv.Atoi(balance)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid balance %q", balance)
+	}
+	if balNum <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "balance must be positive")
+	}
+	if len(categories) == 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "at least one merchant category is required")
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	if expiry <= ts.Seconds {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+
+	err = s.screenParties(ctx, id, account.Name, rec)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, id, rec, balNum)
+	if err != nil {
+		return err
+	}
+
+	change := account.Balance - balNum
+
+	if change < 0 {
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, account, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+		}
+		change = 0
+	}
+
+	account.Balance = change
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	issue := taggedIssue{
+		ID:         issueID,
+		BankID:     id,
+		UserID:     rec,
+		Amount:     balNum,
+		Purpose:    purpose,
+		Categories: categories,
+		Expiry:     expiry,
+		Date:       date,
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(taggedIssueType, []string{issueID})
+	if err != nil {
+		return err
+	}
+	issueJSON, err := json.Marshal(issue)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, issueJSON)
+	if err != nil {
+		return err
+	}
+
+	s.TransferHistory(ctx, id, rec, balance)
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// ReadTaggedIssue returns the purpose-bound payment recorded under issueID.
+func (s *RegulatoryContract) ReadTaggedIssue(ctx contractapi.TransactionContextInterface, issueID string) (*taggedIssue, error) {
+	issue, err := s.readTaggedIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the tagged issue %s does not exist", issueID)
+	}
+	return issue, nil
+}
+
+func (s *RegulatoryContract) readTaggedIssue(ctx contractapi.TransactionContextInterface, issueID string) (*taggedIssue, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(taggedIssueType, []string{issueID})
+	if err != nil {
+		return nil, err
+	}
+	issueJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if issueJSON == nil {
+		return nil, nil
+	}
+	var issue taggedIssue
+	err = json.Unmarshal(issueJSON, &issue)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
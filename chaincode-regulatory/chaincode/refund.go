@@ -0,0 +1,183 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	refundType      = "refund"
+	refundTotalType = "refundTotal"
+)
+
+// refund returns part or all of a bank transfer recorded in the history.
+// Override is set when the regulator issued it instead of the receiving bank.
+type refund struct {
+	ID        string `json:"ID"`
+	HistoryID string `json:"historyID"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Price     int    `json:"price"`
+	Date      string `json:"date"`
+	Override  bool   `json:"override"`
+}
+
+func (s *RegulatoryContract) readHistory(ctx contractapi.TransactionContextInterface, historyID string) (*usageHistory, error) {
+	historyJSON, err := ctx.GetStub().GetState(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if historyJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the history %s does not exist", historyID)
+	}
+	var history usageHistory
+	err = json.Unmarshal(historyJSON, &history)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// RefundedAmount returns how much of a history entry has been refunded so far.
+func (s *RegulatoryContract) RefundedAmount(ctx contractapi.TransactionContextInterface, historyID string) (int, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(refundTotalType, []string{historyID})
+	if err != nil {
+		return 0, err
+	}
+	totalJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if totalJSON == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(totalJSON))
+}
+
+// RefundTransferBank sends price back from the receiving bank of a history entry to the sending bank.
+// The receiving bank has to authorize it, or the regulator can override.
+// Refunds of one entry together can not exceed its original price.
+func (s *RegulatoryContract) RefundTransferBank(ctx contractapi.TransactionContextInterface, historyID string, price string) error {
+	history, err := s.readHistory(ctx, historyID)
+	if err != nil {
+		return err
+	}
+	override := !isBankOperator(ctx, history.Receiver)
+	if override && !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s or the regulator can refund the history %s", history.Receiver, historyID)
+	}
+
+	priceNum, e := strconv.Atoi(price)
+	if e != nil {
+		return e
+	}
+	original, e := strconv.Atoi(history.Price)
+	if e != nil {
+		return e
+	}
+	refunded, err := s.RefundedAmount(ctx, historyID)
+	if err != nil {
+		return err
+	}
+	if priceNum <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	if refunded+priceNum > original {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "refund of %d exceeds the remaining %d of the history %s", priceNum, original-refunded, historyID)
+	}
+
+	sender, err := s.ReadAccount(ctx, history.Receiver)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, history.Sender)
+	if err != nil {
+		return err
+	}
+	if sender.Balance < priceNum {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", sender.ID)
+	}
+	sender.Balance = sender.Balance - priceNum
+	receiver.Balance = receiver.Balance + priceNum
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	ref := refund{
+		ID:        ctx.GetStub().GetTxID(),
+		HistoryID: historyID,
+		From:      sender.ID,
+		To:        receiver.ID,
+		Price:     priceNum,
+		Date:      date,
+		Override:  override,
+	}
+	refJSON, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	refKey, err := ctx.GetStub().CreateCompositeKey(refundType, []string{historyID, ref.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(refKey, refJSON)
+	if err != nil {
+		return err
+	}
+	totalKey, err := ctx.GetStub().CreateCompositeKey(refundTotalType, []string{historyID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(totalKey, []byte(strconv.Itoa(refunded+priceNum)))
+	if err != nil {
+		return err
+	}
+
+	senderJSON, err := json.Marshal(sender)
+	if err != nil {
+		return err
+	}
+	receiverJSON, err := json.Marshal(receiver)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(sender.ID, senderJSON)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(receiver.ID, receiverJSON)
+	if err != nil {
+		return err
+	}
+
+	s.TransferHistory(ctx, receiver.ID, sender.ID, price)
+	return nil
+}
+
+// ReadRefunds returns the refunds made against a history entry.
+func (s *RegulatoryContract) ReadRefunds(ctx contractapi.TransactionContextInterface, historyID string) ([]*refund, error) {
+	refundJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(refundType, []string{historyID})
+	if err != nil {
+		return nil, err
+	}
+	defer refundJSON.Close()
+	var refunds []*refund
+	for refundJSON.HasNext() {
+		queryResponse, err := refundJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var ref refund
+		err = json.Unmarshal(queryResponse.Value, &ref)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, &ref)
+	}
+	return refunds, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 	"strconv"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
 )
 
 // "time"
@@ -21,6 +22,11 @@ type Account struct {
 	ID             string `json:"ID"`
 	Name		   string `json:"name"`
 	Balance 	   int 	  `json:"balance"`	
+	CreditLimit    int    `json:"creditLimit"`
+	CreditRate     int    `json:"creditRate"`
+	CreditDrawn    int    `json:"creditDrawn"`
+	OvernightLoan  int    `json:"overnightLoan"`
+	Locked         int    `json:"locked,omitempty"`
 }
 
 type usageHistory struct {
@@ -29,6 +35,7 @@ type usageHistory struct {
 	Price		   string `json:"price"`
 	Date		   string `json:"date"`
 	Sender 		   string `json:"sender"`
+	Fee			   string `json:"fee,omitempty"`
 }
 
 func (s *RegulatoryContract) InitAccount(ctx contractapi.TransactionContextInterface) error {
@@ -60,7 +67,7 @@ func (s *RegulatoryContract) ReadAccount(ctx contractapi.TransactionContextInter
 		return nil, fmt.Errorf("failed to read world state: %v", err)
 	}
 	if accountJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the asset %s does not exist", id)
 	}
 
 	var account Account
@@ -84,7 +91,7 @@ func (s *RegulatoryContract) UpdateAccount(ctx contractapi.TransactionContextInt
 	}
 
 	if id != "Bank0" {
-		return fmt.Errorf("Only the head office of a bank can issue a CBDC from the central bank!!")
+		return cbdcerr.New(cbdcerr.Unauthorized, "Only the head office of a bank can issue a CBDC from the central bank!!")
 	}
 
 	account.Balance = account.Balance + balNum
@@ -125,10 +132,32 @@ func (s *RegulatoryContract) UpdateSendBalance(ctx contractapi.TransactionContex
 		return e
 	}
 
-	change := account.Balance - balNum
+	err = s.screenParties(ctx, id, account.Name, rec)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, id, rec, balNum)
+	if err != nil {
+		return err
+	}
+	quote, err := s.quoteFee(ctx, feeBankToUser, id, balNum)
+	if err != nil {
+		return err
+	}
+
+	// 수수료는 지급액과 별도로 은행이 부담
+	change := account.Balance - balNum - quote.Fee
 	
 	if change < 0 {
-		return fmt.Errorf("Lack of Balance")
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, account, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+		}
+		change = 0
 	}
 
 	account.Balance = change
@@ -147,10 +176,14 @@ func (s *RegulatoryContract) UpdateSendBalance(ctx contractapi.TransactionContex
 
 	response := ctx.GetStub().InvokeChaincode("userchaincode", queryArgs, "user-channel")
 	if response.Status != 200 {
-		return fmt.Errorf("Failed to query chaincode. Got Error: %s", response.Payload)
+		return cbdcerr.FromResponse(response)
 	}
 
-	s.TransferHistory(ctx, id, rec, balance)
+	err = s.creditFee(ctx, quote, map[string]*Account{id: account})
+	if err != nil {
+		return err
+	}
+	s.transferHistoryFee(ctx, id, rec, balance, quote.Fee)
 	accountJSON, err := json.Marshal(account)
 	if err != nil {
 		return err
@@ -168,10 +201,27 @@ func (s *RegulatoryContract) UpdateUserBalance(ctx contractapi.TransactionContex
 		return e
 	}
 
+	err = s.screenParties(ctx, id, account.Name, rec)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, id, rec, balNum)
+	if err != nil {
+		return err
+	}
+
 	change := account.Balance - balNum
 	
 	if change < 0 {
-		return fmt.Errorf("Lack of Balance")
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, account, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+		}
+		change = 0
 	}
 
 	account.Balance = change
@@ -190,7 +240,7 @@ func (s *RegulatoryContract) UpdateUserBalance(ctx contractapi.TransactionContex
 
 	response := ctx.GetStub().InvokeChaincode("userchaincode", queryArgs, "user-channel")
 	if response.Status != 200 {
-		return fmt.Errorf("Failed to query chaincode. Got Error: %s", response.Payload)
+		return cbdcerr.FromResponse(response)
 	}
 
 	accountJSON, err := json.Marshal(account)
@@ -234,6 +284,11 @@ func (s *RegulatoryContract) ReadTransferHistory(ctx contractapi.TransactionCont
 }
 
 func (s *RegulatoryContract) TransferHistory(ctx contractapi.TransactionContextInterface, rec string, sen string, price string) error {
+	return s.transferHistoryFee(ctx, rec, sen, price, 0)
+}
+
+// transferHistoryFee records a transfer together with the fee the payer paid on it.
+func (s *RegulatoryContract) transferHistoryFee(ctx contractapi.TransactionContextInterface, rec string, sen string, price string, fee int) error {
 	history, err := s.ReadTransferHistory(ctx)
 	if err != nil {
 		return err
@@ -248,6 +303,9 @@ func (s *RegulatoryContract) TransferHistory(ctx contractapi.TransactionContextI
 		Date:		customTime,
 		Sender:		sen,
 	}
+	if fee > 0 {
+		his.Fee = strconv.Itoa(fee)
+	}
 	hisJSON, err := json.Marshal(his)
 	if err != nil {
 		return err
@@ -270,14 +328,35 @@ func (s *RegulatoryContract) TransferBalanceBank(ctx contractapi.TransactionCont
 		return e
 	}
 
-	sBal := sender.Balance - priceNum
+	err = s.screenParties(ctx, id, sender.Name, rec, receiver.Name)
+	if err != nil {
+		return err
+	}
+	quote, err := s.quoteFee(ctx, feeInterbank, id, priceNum)
+	if err != nil {
+		return err
+	}
+
+	sBal := sender.Balance - priceNum - quote.Fee
 	rBal := receiver.Balance + priceNum
 	if sBal < 0 {
-		return fmt.Errorf("Lack of balance %s's Account", id)
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, sender, -sBal)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+		}
+		sBal = 0
 	}
 
 	sender.Balance = sBal
 	receiver.Balance = rBal
+	err = s.creditFee(ctx, quote, map[string]*Account{id: sender, rec: receiver})
+	if err != nil {
+		return err
+	}
 
 	senderJSON, sErr := json.Marshal(sender)
 	if sErr != nil {
@@ -290,6 +369,6 @@ func (s *RegulatoryContract) TransferBalanceBank(ctx contractapi.TransactionCont
 	ctx.GetStub().PutState(id, senderJSON)
 	ctx.GetStub().PutState(rec, receiverJSON)
 
-	s.TransferHistory(ctx, rec, id, price)
+	s.transferHistoryFee(ctx, rec, id, price, quote.Fee)
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,119 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const receivableSettlementType = "receivableSettlement"
+
+// bankReceivable is the running total the user channel owes a bank.
+type bankReceivable struct {
+	BankID string `json:"bankID"`
+	Total  int    `json:"total"`
+}
+
+// receivableSettlement is how much of a bank's receivable on the user channel
+// has been credited to its account.
+type receivableSettlement struct {
+	BankID  string `json:"bankID"`
+	Settled int    `json:"settled"`
+	Date    string `json:"date"`
+}
+
+// queryUser evaluates a function of the user chaincode and returns its payload.
+// Anything the function writes is discarded.
+func queryUser(ctx contractapi.TransactionContextInterface, params ...string) ([]byte, error) {
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("userchaincode", queryArgs, "user-channel")
+	if response.Status != 200 {
+		return nil, cbdcerr.FromResponse(response)
+	}
+	return response.Payload, nil
+}
+
+// SettleBankReceivable credits a bank with what the user channel owes it beyond what
+// has already been settled, such as clawed back tagged balances and payment fees. The
+// user chaincode keeps one entry per transaction and ReadBankReceivable sums them.
+// bankID can also be CentralBankFeeAccount, for the user payment fees due to the
+// central bank. It returns the amount credited.
+func (s *RegulatoryContract) SettleBankReceivable(ctx contractapi.TransactionContextInterface, bankID string) (int, error) {
+	if !isBankOperator(ctx, bankID) && !isRegulator(ctx) {
+		return 0, cbdcerr.New(cbdcerr.Unauthorized, "only an operator of %s or the regulator can settle its receivable", bankID)
+	}
+	account, err := s.readPayee(ctx, bankID)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := queryUser(ctx, "ReadBankReceivable", bankID)
+	if err != nil {
+		return 0, err
+	}
+	var receivable bankReceivable
+	err = json.Unmarshal(payload, &receivable)
+	if err != nil {
+		return 0, err
+	}
+	settlement, err := s.readReceivableSettlement(ctx, bankID)
+	if err != nil {
+		return 0, err
+	}
+	amount := receivable.Total - settlement.Settled
+	if amount <= 0 {
+		return 0, nil
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	account.Balance = account.Balance + amount
+	settlement.Settled = receivable.Total
+	settlement.Date = date
+	err = s.putAccount(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(receivableSettlementType, []string{bankID})
+	if err != nil {
+		return 0, err
+	}
+	settlementJSON, err := json.Marshal(settlement)
+	if err != nil {
+		return 0, err
+	}
+	err = ctx.GetStub().PutState(key, settlementJSON)
+	if err != nil {
+		return 0, err
+	}
+	s.TransferHistory(ctx, bankID, "User Channel", strconv.Itoa(amount))
+	return amount, nil
+}
+
+func (s *RegulatoryContract) readReceivableSettlement(ctx contractapi.TransactionContextInterface, bankID string) (*receivableSettlement, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(receivableSettlementType, []string{bankID})
+	if err != nil {
+		return nil, err
+	}
+	settlementJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	settlement := receivableSettlement{BankID: bankID}
+	if settlementJSON == nil {
+		return &settlement, nil
+	}
+	err = json.Unmarshal(settlementJSON, &settlement)
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
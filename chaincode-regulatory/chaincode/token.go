@@ -0,0 +1,140 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const tokenIssueType = "tokenIssue"
+
+// tokenIssue is a UTXO token debited from a bank, to be created for its owner
+// by IssueToken on the user channel.
+type tokenIssue struct {
+	ID     string `json:"ID"`
+	BankID string `json:"bankID"`
+	Owner  string `json:"owner"`
+	Amount int    `json:"amount"`
+	Date   string `json:"date"`
+}
+
+// UpdateSendTokenBalance pays balance from a bank as a UTXO token owned by the client identity rec.
+// The bank is debited here and the issue recorded under issueID; the user chaincode
+// creates the token with IssueToken.
+func (s *RegulatoryContract) UpdateSendTokenBalance(ctx contractapi.TransactionContextInterface, issueID string, id string, rec string, balance string) error {
+	if !isBankOperator(ctx, id) && !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only an operator of %s or the regulator can pay from it", id)
+	}
+	existing, err := s.readTokenIssue(ctx, issueID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the token issue %s already exists", issueID)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	balNum, e := strconv.Atoi(balance)
+	if e != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid balance %q", balance)
+	}
+	if balNum <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "balance must be positive")
+	}
+	if rec == "" {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "token owner is required")
+	}
+
+	err = s.screenParties(ctx, id, account.Name, rec)
+	if err != nil {
+		return err
+	}
+	err = s.checkTransfer(ctx, id, rec, balNum)
+	if err != nil {
+		return err
+	}
+
+	change := account.Balance - balNum
+
+	if change < 0 {
+		// 잔액 부족분은 일중 신용한도에서 자동 인출
+		drawn, err := s.drawIntradayCredit(ctx, account, -change)
+		if err != nil {
+			return err
+		}
+		if !drawn {
+			return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of Balance")
+		}
+		change = 0
+	}
+
+	account.Balance = change
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	issue := tokenIssue{
+		ID:     issueID,
+		BankID: id,
+		Owner:  rec,
+		Amount: balNum,
+		Date:   date,
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(tokenIssueType, []string{issueID})
+	if err != nil {
+		return err
+	}
+	issueJSON, err := json.Marshal(issue)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, issueJSON)
+	if err != nil {
+		return err
+	}
+
+	s.TransferHistory(ctx, id, rec, balance)
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// ReadTokenIssue returns the token issue recorded under issueID.
+func (s *RegulatoryContract) ReadTokenIssue(ctx contractapi.TransactionContextInterface, issueID string) (*tokenIssue, error) {
+	issue, err := s.readTokenIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the token issue %s does not exist", issueID)
+	}
+	return issue, nil
+}
+
+func (s *RegulatoryContract) readTokenIssue(ctx contractapi.TransactionContextInterface, issueID string) (*tokenIssue, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(tokenIssueType, []string{issueID})
+	if err != nil {
+		return nil, err
+	}
+	issueJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if issueJSON == nil {
+		return nil, nil
+	}
+	var issue tokenIssue
+	err = json.Unmarshal(issueJSON, &issue)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
@@ -0,0 +1,403 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+)
+
+const (
+	watchListType       = "watchList"
+	watchListUpdateType = "watchListUpdate"
+	watchListVersionKey = "watchListVersion"
+	watchListMatchType  = "watchListMatch"
+)
+
+// WatchListMatch is the record of an operation that screened a party on the watch list,
+// stored under its tx ID.
+type WatchListMatch struct {
+	TxID     string   `json:"txID"`
+	Function string   `json:"function"`
+	Matches  []string `json:"matches"`
+	Version  int      `json:"version"`
+	Date     string   `json:"date"`
+}
+
+// txMatches holds the watch-list match of each running transaction, by tx ID.
+var txMatches = struct {
+	sync.Mutex
+	m map[string]*WatchListMatch
+}{m: make(map[string]*WatchListMatch)}
+
+// watchListEntry is a screened entity. Only the hash of its identifier is stored.
+type watchListEntry struct {
+	Hash    string `json:"hash"`
+	Version int    `json:"version"`
+}
+
+// watchListUpdate records one version of the watch list.
+type watchListUpdate struct {
+	Version int      `json:"version"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Actor   string   `json:"actor"`
+	Date    string   `json:"date"`
+}
+
+// watchListMatch is returned when a screened party is on the watch list.
+type watchListMatch struct {
+	Matches []string `json:"matches"`
+	Version int      `json:"version"`
+}
+
+// WatchListHash is how identifiers are hashed before they are stored or screened:
+// the hex SHA-256 of the lower cased identifier with its spaces collapsed.
+func WatchListHash(id string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(id)), " ")
+	digest := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(digest[:])
+}
+
+// UpdateWatchList adds and removes hashed identifiers as a new watch list version.
+// Only the regulator can update it.
+func (s *RegulatoryContract) UpdateWatchList(ctx contractapi.TransactionContextInterface, added []string, removed []string) (int, error) {
+	if !isRegulator(ctx) {
+		return 0, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can update the watch list")
+	}
+	for _, hash := range append(append([]string{}, added...), removed...) {
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return 0, cbdcerr.New(cbdcerr.InvalidArgument, "watch list entries must be hex encoded SHA-256 hashes: %s", hash)
+		}
+	}
+	version, err := s.ReadWatchListVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version = version + 1
+
+	for _, hash := range added {
+		key, err := ctx.GetStub().CreateCompositeKey(watchListType, []string{hash})
+		if err != nil {
+			return 0, err
+		}
+		entryJSON, err := json.Marshal(watchListEntry{Hash: hash, Version: version})
+		if err != nil {
+			return 0, err
+		}
+		err = ctx.GetStub().PutState(key, entryJSON)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, hash := range removed {
+		key, err := ctx.GetStub().CreateCompositeKey(watchListType, []string{hash})
+		if err != nil {
+			return 0, err
+		}
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	actor, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, err
+	}
+	date, err := txDate(ctx)
+	if err != nil {
+		return 0, err
+	}
+	update := watchListUpdate{
+		Version: version,
+		Added:   added,
+		Removed: removed,
+		Actor:   actor,
+		Date:    date,
+	}
+	updateJSON, err := json.Marshal(update)
+	if err != nil {
+		return 0, err
+	}
+	updateKey, err := ctx.GetStub().CreateCompositeKey(watchListUpdateType, []string{fmt.Sprintf("%010d", version)})
+	if err != nil {
+		return 0, err
+	}
+	err = ctx.GetStub().PutState(updateKey, updateJSON)
+	if err != nil {
+		return 0, err
+	}
+	err = ctx.GetStub().PutState(watchListVersionKey, []byte(strconv.Itoa(version)))
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// ReadWatchListVersion returns the current watch list version, 0 before the first update.
+func (s *RegulatoryContract) ReadWatchListVersion(ctx contractapi.TransactionContextInterface) (int, error) {
+	versionJSON, err := ctx.GetStub().GetState(watchListVersionKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if versionJSON == nil {
+		return 0, nil
+	}
+	return strconv.Atoi(string(versionJSON))
+}
+
+// ReadWatchListUpdates returns the history of watch list versions.
+func (s *RegulatoryContract) ReadWatchListUpdates(ctx contractapi.TransactionContextInterface) ([]*watchListUpdate, error) {
+	updateJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(watchListUpdateType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer updateJSON.Close()
+	var updates []*watchListUpdate
+	for updateJSON.HasNext() {
+		queryResponse, err := updateJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var update watchListUpdate
+		err = json.Unmarshal(queryResponse.Value, &update)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, &update)
+	}
+	return updates, nil
+}
+
+// CheckWatchList returns which of the given hashes are on the watch list.
+// The user chaincode calls it with hashes so names never leave the user channel.
+func (s *RegulatoryContract) CheckWatchList(ctx contractapi.TransactionContextInterface, hashes []string) (*watchListMatch, error) {
+	version, err := s.ReadWatchListVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	match := watchListMatch{Matches: []string{}, Version: version}
+	for _, hash := range hashes {
+		key, err := ctx.GetStub().CreateCompositeKey(watchListType, []string{hash})
+		if err != nil {
+			return nil, err
+		}
+		entryJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read world state: %v", err)
+		}
+		if entryJSON != nil {
+			match.Matches = append(match.Matches, hash)
+		}
+	}
+	return &match, nil
+}
+
+// screenParties fails an operation with a Blocked error naming the matched hashes
+// when any of the parties is on the watch list. A match also stores a WatchListMatch
+// record and emits a WatchListMatch event, which the WatchList wrapper commits with
+// the refusal.
+func (s *RegulatoryContract) screenParties(ctx contractapi.TransactionContextInterface, parties ...string) error {
+	var hashes []string
+	for _, party := range parties {
+		hashes = append(hashes, WatchListHash(party))
+	}
+	match, err := s.CheckWatchList(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	if len(match.Matches) == 0 {
+		return nil
+	}
+
+	date, err := txDate(ctx)
+	if err != nil {
+		return err
+	}
+	// 한 거래에서 여러 번 일치하면 기록 하나에 모음
+	txID := ctx.GetStub().GetTxID()
+	txMatches.Lock()
+	record, ok := txMatches.m[txID]
+	if !ok {
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		record = &WatchListMatch{TxID: txID, Function: function, Version: match.Version, Date: date}
+		txMatches.m[txID] = record
+	}
+	record.Matches = append(record.Matches, match.Matches...)
+	txMatches.Unlock()
+	key, err := ctx.GetStub().CreateCompositeKey(watchListMatchType, []string{record.TxID})
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, recordJSON)
+	if err != nil {
+		return err
+	}
+	err = emitEvent(ctx, "WatchListMatch", recordJSON)
+	if err != nil {
+		return err
+	}
+	return cbdcerr.New(cbdcerr.Blocked, "operation blocked: a party is on the watch list (version %d): %s", match.Version, strings.Join(match.Matches, ","))
+}
+
+// ReadWatchListMatch returns the watch-list match recorded by a transaction.
+func (s *RegulatoryContract) ReadWatchListMatch(ctx contractapi.TransactionContextInterface, txID string) (*WatchListMatch, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can read watch-list matches")
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(watchListMatchType, []string{txID})
+	if err != nil {
+		return nil, err
+	}
+	matchJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if matchJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the transaction %s has no watch-list match", txID)
+	}
+	var match WatchListMatch
+	err = json.Unmarshal(matchJSON, &match)
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// bufferedStub holds back the writes and the event of a transaction until it is flushed.
+// Reads go to the stub, which does not see the transaction's own writes either. It notes
+// calls to chaincodes on the same channel, whose writes cannot be held back.
+type bufferedStub struct {
+	shim.ChaincodeStubInterface
+	writes  []func() error
+	invoked bool
+}
+
+func (b *bufferedStub) PutState(key string, value []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.PutState(key, value) })
+	return nil
+}
+
+func (b *bufferedStub) DelState(key string) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.DelState(key) })
+	return nil
+}
+
+func (b *bufferedStub) SetStateValidationParameter(key string, ep []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.SetStateValidationParameter(key, ep) })
+	return nil
+}
+
+func (b *bufferedStub) PutPrivateData(collection string, key string, value []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.PutPrivateData(collection, key, value) })
+	return nil
+}
+
+func (b *bufferedStub) DelPrivateData(collection string, key string) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.DelPrivateData(collection, key) })
+	return nil
+}
+
+func (b *bufferedStub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	b.writes = append(b.writes, func() error {
+		return b.ChaincodeStubInterface.SetPrivateDataValidationParameter(collection, key, ep)
+	})
+	return nil
+}
+
+func (b *bufferedStub) SetEvent(name string, payload []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.SetEvent(name, payload) })
+	return nil
+}
+
+func (b *bufferedStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) peer.Response {
+	if channel == "" || channel == b.GetChannelID() {
+		b.invoked = true
+	}
+	return b.ChaincodeStubInterface.InvokeChaincode(chaincodeName, args, channel)
+}
+
+// flush makes the held back writes, in order.
+func (b *bufferedStub) flush() error {
+	for _, write := range b.writes {
+		err := write()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type watchListChaincode struct {
+	cc shim.Chaincode
+}
+
+// WatchList wraps the contract chaincode so that an operation blocked by the watch list is
+// committed as a refusal: the writes the operation made are dropped, its WatchListMatch
+// record and event are kept, and its Blocked error is returned with status
+// cbdcerr.Refused so that the peers endorse the transaction. An operation that already
+// called a chaincode on this channel, such as the asset chaincode, fails instead.
+func WatchList(cc shim.Chaincode) shim.Chaincode {
+	return &watchListChaincode{cc: cc}
+}
+
+func (w *watchListChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return w.cc.Init(stub)
+}
+
+func (w *watchListChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	txID := stub.GetTxID()
+	defer func() {
+		txMatches.Lock()
+		delete(txMatches.m, txID)
+		txMatches.Unlock()
+	}()
+
+	buffered := &bufferedStub{ChaincodeStubInterface: stub}
+	response := w.cc.Invoke(buffered)
+	if response.Status < shim.ERRORTHRESHOLD {
+		err := buffered.flush()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return response
+	}
+
+	txMatches.Lock()
+	match := txMatches.m[txID]
+	txMatches.Unlock()
+	refusal := cbdcerr.Parse(response.Message)
+	if match == nil || refusal.Code != cbdcerr.Blocked || buffered.invoked {
+		return response
+	}
+	key, err := stub.CreateCompositeKey(watchListMatchType, []string{txID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	matchJSON, err := json.Marshal(match)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(key, matchJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.SetEvent("WatchListMatch", matchJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return cbdcerr.Refusal(refusal, matchJSON)
+}
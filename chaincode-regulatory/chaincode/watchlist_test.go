@@ -0,0 +1,45 @@
+package chaincode_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-regulatory/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchListMatch(t *testing.T) {
+	s := chaincode.RegulatoryContract{}
+	l := newLedger(t, map[string]int{"Bank1": 500, "Bank2": 0, "Bank3": 0})
+	listed := chaincode.WatchListHash("Bank2")
+	require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.UpdateWatchList(ctx, []string{listed}, nil)
+		return err
+	}))
+	transfer := func(to string) error {
+		return l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.TransferBalanceBank(ctx, "Bank1", to, "100")
+		})
+	}
+
+	// 차단된 이체도 일치 기록과 이벤트는 커밋
+	requireCode(t, transfer("Bank2"), cbdcerr.Blocked, "operation blocked: a party is on the watch list (version 1): "+listed+","+listed)
+	txID := fmt.Sprintf("%s/tx%03d", t.Name(), l.n)
+	require.Equal(t, 500, l.balance("Bank1"))
+	require.Equal(t, 0, l.balance("Bank2"))
+	require.Equal(t, "WatchListMatch", l.lastEvent().EventName)
+
+	var match *chaincode.WatchListMatch
+	require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		match, err = s.ReadWatchListMatch(ctx, txID)
+		return err
+	}))
+	require.Equal(t, &chaincode.WatchListMatch{TxID: txID, Matches: []string{listed, listed}, Version: 1, Date: "2026-10-01 09:00"}, match)
+
+	require.NoError(t, transfer("Bank3"))
+	require.Equal(t, 400, l.balance("Bank1"))
+	require.Equal(t, 100, l.balance("Bank3"))
+}
@@ -0,0 +1,40 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// accountCache keeps the accounts a batch touches in memory. GetState does not
+// see writes of the same transaction, so every account is read once, updated in
+// place and written once by flush.
+type accountCache struct {
+	accounts map[string]*UserAccount
+	order    []string
+}
+
+func newAccountCache() *accountCache {
+	return &accountCache{accounts: make(map[string]*UserAccount)}
+}
+
+func (c *accountCache) get(ctx contractapi.TransactionContextInterface, s *UserContract, id string) (*UserAccount, error) {
+	if account, ok := c.accounts[id]; ok {
+		return account, nil
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.accounts[id] = account
+	c.order = append(c.order, id)
+	return account, nil
+}
+
+func (c *accountCache) flush(ctx contractapi.TransactionContextInterface, s *UserContract) error {
+	for _, id := range c.order {
+		err := s.putAccount(ctx, c.accounts[id])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
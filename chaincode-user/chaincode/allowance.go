@@ -0,0 +1,201 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const erc20AllowanceType = "erc20Allowance"
+
+// erc20Allowance lets spender pull from owner's account, both named by client identity.
+// Amount is what is left of the cap. Expiry is unix seconds, zero means it does not expire.
+// When PeriodLimit is set at most PeriodLimit can be spent in each Period seconds
+// counted from PeriodStart.
+type erc20Allowance struct {
+	Owner       string `json:"owner"`
+	Spender     string `json:"spender"`
+	Amount      int    `json:"amount"`
+	Expiry      int64  `json:"expiry,omitempty"`
+	PeriodLimit int    `json:"periodLimit,omitempty"`
+	Period      int64  `json:"period,omitempty"`
+	PeriodStart int64  `json:"periodStart,omitempty"`
+	PeriodSpent int    `json:"periodSpent,omitempty"`
+}
+
+// roll moves the allowance into the period containing now.
+func (a *erc20Allowance) roll(now int64) {
+	if a.PeriodLimit == 0 || now < a.PeriodStart+a.Period {
+		return
+	}
+	a.PeriodStart = a.PeriodStart + (now-a.PeriodStart)/a.Period*a.Period
+	a.PeriodSpent = 0
+}
+
+// available returns what can be spent at now.
+func (a *erc20Allowance) available(now int64) int {
+	if a.Expiry != 0 && now >= a.Expiry {
+		return 0
+	}
+	a.roll(now)
+	available := a.Amount
+	if a.PeriodLimit > 0 && a.PeriodLimit-a.PeriodSpent < available {
+		available = a.PeriodLimit - a.PeriodSpent
+	}
+	return available
+}
+
+// ApproveWithLimits lets spender transfer up to value from the caller's account until expiry
+// (unix seconds, 0 for none), and at most periodLimit in every period seconds (0 for none).
+// It replaces any previous allowance of spender.
+func (s *UserContract) ApproveWithLimits(ctx contractapi.TransactionContextInterface, spender string, value int, expiry int64, periodLimit int, period int64) error {
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return err
+	}
+	if value < 0 || periodLimit < 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "allowance cannot be negative")
+	}
+	if periodLimit > 0 && period <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "a period limit needs a positive period")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiry != 0 && expiry <= now.Unix() {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+	if _, err := s.clientAccount(ctx, owner); err != nil {
+		return err
+	}
+
+	allowance := erc20Allowance{
+		Owner:   owner,
+		Spender: spender,
+		Amount:  value,
+		Expiry:  expiry,
+	}
+	if periodLimit > 0 {
+		allowance.PeriodLimit = periodLimit
+		allowance.Period = period
+		allowance.PeriodStart = now.Unix()
+	}
+	err = s.putAllowance(ctx, &allowance)
+	if err != nil {
+		return err
+	}
+	eventJSON, err := json.Marshal(erc20Event{Owner: owner, Spender: spender, Value: value})
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, "Approval", eventJSON)
+}
+
+// RevokeAllowance removes the allowance the caller gave spender.
+func (s *UserContract) RevokeAllowance(ctx contractapi.TransactionContextInterface, spender string) error {
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(erc20AllowanceType, []string{owner, spender})
+	if err != nil {
+		return err
+	}
+	allowanceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read world state: %v", err)
+	}
+	if allowanceJSON == nil {
+		return cbdcerr.New(cbdcerr.NotFound, "there is no allowance for %s", spender)
+	}
+	err = ctx.GetStub().DelState(key)
+	if err != nil {
+		return err
+	}
+	eventJSON, err := json.Marshal(erc20Event{Owner: owner, Spender: spender, Value: 0})
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, "Approval", eventJSON)
+}
+
+// ReadAllowances returns the allowances owner has given.
+func (s *UserContract) ReadAllowances(ctx contractapi.TransactionContextInterface, owner string) ([]*erc20Allowance, error) {
+	allowanceJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(erc20AllowanceType, []string{owner})
+	if err != nil {
+		return nil, err
+	}
+	defer allowanceJSON.Close()
+	var allowances []*erc20Allowance
+	for allowanceJSON.HasNext() {
+		queryResponse, err := allowanceJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var allowance erc20Allowance
+		err = json.Unmarshal(queryResponse.Value, &allowance)
+		if err != nil {
+			return nil, err
+		}
+		allowances = append(allowances, &allowance)
+	}
+	return allowances, nil
+}
+
+// spendAllowance takes value off the allowance owner gave spender.
+func (s *UserContract) spendAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string, value int) error {
+	allowance, err := s.readAllowance(ctx, owner, spender)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if allowance.Expiry != 0 && now.Unix() >= allowance.Expiry {
+		return cbdcerr.New(cbdcerr.Rejected, "the allowance of %s has expired", spender)
+	}
+	if allowance.available(now.Unix()) < value {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "the allowance of %s is less than %d", spender, value)
+	}
+	allowance.Amount = allowance.Amount - value
+	if allowance.PeriodLimit > 0 {
+		allowance.PeriodSpent = allowance.PeriodSpent + value
+	}
+	return s.putAllowance(ctx, allowance)
+}
+
+func (s *UserContract) readAllowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (*erc20Allowance, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(erc20AllowanceType, []string{owner, spender})
+	if err != nil {
+		return nil, err
+	}
+	allowanceJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	allowance := erc20Allowance{Owner: owner, Spender: spender}
+	if allowanceJSON == nil {
+		return &allowance, nil
+	}
+	err = json.Unmarshal(allowanceJSON, &allowance)
+	if err != nil {
+		return nil, err
+	}
+	return &allowance, nil
+}
+
+func (s *UserContract) putAllowance(ctx contractapi.TransactionContextInterface, allowance *erc20Allowance) error {
+	key, err := ctx.GetStub().CreateCompositeKey(erc20AllowanceType, []string{allowance.Owner, allowance.Spender})
+	if err != nil {
+		return err
+	}
+	allowanceJSON, err := json.Marshal(allowance)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, allowanceJSON)
+}
@@ -0,0 +1,102 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const amlActivityType = "amlActivity"
+
+// amlResult is the screening outcome returned by the regulatory chaincode.
+type amlResult struct {
+	Sender   string   `json:"sender"`
+	Receiver string   `json:"receiver"`
+	Price    int      `json:"price"`
+	Score    int      `json:"score"`
+	Decision string   `json:"decision"`
+	Reasons  []string `json:"reasons"`
+	Window   int64    `json:"window"`
+}
+
+// amlActivity is a past transfer of a sender, kept on this channel for structuring checks.
+type amlActivity struct {
+	Price int   `json:"price"`
+	Time  int64 `json:"time"`
+}
+
+// screenTransfer has the regulatory chaincode score a user transfer against its AML rules,
+// given the sender's activity kept here, and records the transfer in that activity.
+// A flagged transfer emits a SuspiciousActivity event, a blocked one fails.
+func (s *UserContract) screenTransfer(ctx contractapi.TransactionContextInterface, sender string, rec string, price int) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	activityJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(amlActivityType, []string{sender})
+	if err != nil {
+		return err
+	}
+	defer activityJSON.Close()
+	var past []*amlActivity
+	var keys []string
+	for activityJSON.HasNext() {
+		queryResponse, err := activityJSON.Next()
+		if err != nil {
+			return err
+		}
+		var activity amlActivity
+		err = json.Unmarshal(queryResponse.Value, &activity)
+		if err != nil {
+			return err
+		}
+		past = append(past, &activity)
+		keys = append(keys, queryResponse.Key)
+	}
+	pastJSON, err := json.Marshal(past)
+	if err != nil {
+		return err
+	}
+
+	payload, err := queryRegulatory(ctx, "ScreenTransfer", sender, rec, strconv.Itoa(price), string(pastJSON))
+	if err != nil {
+		return err
+	}
+	var result amlResult
+	err = json.Unmarshal(payload, &result)
+	if err != nil {
+		return err
+	}
+	if result.Decision == "block" {
+		return cbdcerr.New(cbdcerr.Blocked, "transfer from %s to %s blocked by AML rules: %v", sender, rec, result.Reasons)
+	}
+
+	// 구조화 기간이 지난 기록은 삭제
+	for i, activity := range past {
+		if activity.Time < now.Unix()-result.Window {
+			err = ctx.GetStub().DelState(keys[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(amlActivityType, []string{sender, ctx.GetStub().GetTxID(), rec})
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(amlActivity{Price: price, Time: now.Unix()})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, recordJSON)
+	if err != nil {
+		return err
+	}
+
+	if result.Decision == "flag" {
+		return emitEvent(ctx, "SuspiciousActivity", payload)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	batchType = "batch"
+
+	linePaid     = "paid"
+	lineRejected = "rejected"
+)
+
+// BatchLine is one payment of a bank batch.
+type BatchLine struct {
+	UserID string `json:"userID"`
+	Amount int    `json:"amount"`
+}
+
+// BatchResult is the outcome of one line of a bank batch.
+type BatchResult struct {
+	UserID string       `json:"userID"`
+	Amount int          `json:"amount"`
+	Status string       `json:"status"`
+	Code   cbdcerr.Code `json:"code,omitempty"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// batchRecord is a bank batch the regulatory chaincode committed with UpdateSendBalanceBatch,
+// with the lines it debited the bank for reported paid.
+type batchRecord struct {
+	ID           string         `json:"ID"`
+	BankID       string         `json:"bankID"`
+	AllOrNothing bool           `json:"allOrNothing"`
+	Results      []*BatchResult `json:"results"`
+}
+
+// ReviewBatch checks the lines of a bank batch, a JSON list of BatchLine, against the
+// user accounts without paying them. The regulatory chaincode calls it before debiting
+// the bank. With allOrNothing a rejected line fails the call, otherwise it is reported.
+func (s *UserContract) ReviewBatch(ctx contractapi.TransactionContextInterface, lines string, allOrNothing bool) ([]*BatchResult, error) {
+	if !isBankOrRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only a bank or the regulator can review a batch")
+	}
+	var batch []*BatchLine
+	err := json.Unmarshal([]byte(lines), &batch)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid batch lines: %v", err)
+	}
+
+	accounts := newAccountCache()
+	var results []*BatchResult
+	for i, line := range batch {
+		result := BatchResult{UserID: line.UserID, Amount: line.Amount, Status: linePaid}
+		reason := s.creditBatchLine(ctx, accounts, line.UserID, line.Amount)
+		if reason != nil {
+			if allOrNothing {
+				return nil, cbdcerr.New(reason.Code, "line %d of the batch to %s: %s", i+1, line.UserID, reason.Message)
+			}
+			result.Status = lineRejected
+			result.Code = reason.Code
+			result.Reason = reason.Message
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// 은행에서 여러 사용자에게 일괄 지급
+// UpdateAccountBatch credits the paid lines of a batch the regulatory chaincode committed
+// after debiting the bank, and writes a history entry per line. Each batch is applied once.
+// A line that can no longer be credited is owed back to the bank, as is the whole batch
+// when it is all or nothing.
+func (s *UserContract) UpdateAccountBatch(ctx contractapi.TransactionContextInterface, batchID string) ([]*BatchResult, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can apply a batch")
+	}
+	payload, err := queryRegulatory(ctx, "ReadBatch", batchID)
+	if err != nil {
+		return nil, err
+	}
+	var batch batchRecord
+	err = json.Unmarshal(payload, &batch)
+	if err != nil {
+		return nil, err
+	}
+	err = s.markApplied(ctx, batchType, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := newAccountCache()
+	var results []*BatchResult
+	var history []*AccountHistory
+	returned := 0
+	for _, line := range batch.Results {
+		if line.Status != linePaid {
+			continue
+		}
+		result := BatchResult{UserID: line.UserID, Amount: line.Amount, Status: linePaid}
+		reason := s.creditBatchLine(ctx, accounts, line.UserID, line.Amount)
+		if reason != nil {
+			result.Status = lineRejected
+			result.Code = reason.Code
+			result.Reason = reason.Message
+			returned = returned + line.Amount
+		} else {
+			history = append(history, &AccountHistory{Receiver: line.UserID, Price: strconv.Itoa(line.Amount), Sender: batch.BankID})
+		}
+		results = append(results, &result)
+	}
+
+	if batch.AllOrNothing && returned > 0 {
+		total := 0
+		for _, result := range results {
+			if result.Status == linePaid {
+				result.Status = lineRejected
+				result.Code = cbdcerr.Rejected
+				result.Reason = "another line of the batch was rejected"
+			}
+			total = total + result.Amount
+		}
+		return results, s.oweBank(ctx, batch.BankID, total)
+	}
+	if returned > 0 {
+		err = s.oweBank(ctx, batch.BankID, returned)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = accounts.flush(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	err = s.transferHistories(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// creditBatchLine adds amount to the cached account of userID, or returns why it cannot.
+func (s *UserContract) creditBatchLine(ctx contractapi.TransactionContextInterface, accounts *accountCache, userID string, amount int) *cbdcerr.Error {
+	account, err := accounts.get(ctx, s, userID)
+	switch {
+	case err != nil:
+		return cbdcerr.From(err)
+	case amount <= 0:
+		return &cbdcerr.Error{Code: cbdcerr.InvalidArgument, Message: "amount must be positive"}
+	case account.Balance+amount > MAX_VAL:
+		return &cbdcerr.Error{Code: cbdcerr.LimitExceeded, Message: fmt.Sprintf("Individuals cannot own more than %d in CBDC.", MAX_VAL)}
+	}
+	if err := s.screenAccounts(ctx, account); err != nil {
+		return cbdcerr.From(err)
+	}
+	account.Balance = account.Balance + amount
+	return nil
+}
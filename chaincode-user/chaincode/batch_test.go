@@ -0,0 +1,78 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// batches is the regulatory chaincode with the batches it committed, as JSON.
+func batches(committed map[string]string) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] != "ReadBatch" {
+			return screen(args)
+		}
+		return shim.Success([]byte(committed[args[1]]))
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 0, "User2": 950})
+	lines := `[{"userID":"User1","amount":100},{"userID":"User2","amount":100}]`
+	paid := `[{"userID":"User1","amount":100,"status":"paid"},{"userID":"User2","amount":100,"status":"paid"}]`
+	l.peer("regulatorychaincode", "regulatory-channel", batches(map[string]string{
+		"batch1": `{"ID":"batch1","bankID":"Bank1","allOrNothing":true,"results":` + paid + `}`,
+		"batch2": `{"ID":"batch2","bankID":"Bank1","allOrNothing":false,"results":` + paid + `}`,
+	}))
+	review := func(allOrNothing bool) ([]*chaincode.BatchResult, error) {
+		var results []*chaincode.BatchResult
+		err := l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			results, err = s.ReviewBatch(ctx, lines, allOrNothing)
+			return err
+		})
+		return results, err
+	}
+	apply := func(batchID string) ([]*chaincode.BatchResult, error) {
+		var results []*chaincode.BatchResult
+		err := l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			results, err = s.UpdateAccountBatch(ctx, batchID)
+			return err
+		})
+		return results, err
+	}
+
+	_, err := review(true)
+	requireCode(t, err, cbdcerr.LimitExceeded, "line 2 of the batch to User2: Individuals cannot own more than 1000 in CBDC.")
+	results, err := review(false)
+	require.NoError(t, err)
+	require.Equal(t, "paid", results[0].Status)
+	require.Equal(t, &chaincode.BatchResult{UserID: "User2", Amount: 100, Status: "rejected", Code: cbdcerr.LimitExceeded, Reason: "Individuals cannot own more than 1000 in CBDC."}, results[1])
+
+	// 전부 아니면 전무: 한 줄이라도 거절되면 일괄 금액 전체를 은행에 반환
+	results, err = apply("batch1")
+	require.NoError(t, err)
+	require.Equal(t, "rejected", results[0].Status)
+	require.Equal(t, "another line of the batch was rejected", results[0].Reason)
+	require.Equal(t, 0, l.balance("User1"))
+	require.Equal(t, 950, l.balance("User2"))
+	require.Equal(t, 200, receivable(l, "Bank1"))
+	_, err = apply("batch1")
+	requireCode(t, err, cbdcerr.AlreadyExists, "the batch batch1 has already been applied")
+
+	results, err = apply("batch2")
+	require.NoError(t, err)
+	require.Equal(t, "paid", results[0].Status)
+	require.Equal(t, "rejected", results[1].Status)
+	require.Equal(t, 100, l.balance("User1"))
+	require.Equal(t, 950, l.balance("User2"))
+	require.Equal(t, 300, receivable(l, "Bank1"))
+}
@@ -0,0 +1,313 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	conditionalPaymentType = "conditionalPayment"
+
+	conditionTime   = "time"
+	conditionCoSign = "cosign"
+	conditionHash   = "hash"
+
+	paymentLocked    = "locked"
+	paymentReleased  = "released"
+	paymentReclaimed = "reclaimed"
+)
+
+// ConditionalPayment is an amount escrowed from the sender until its condition is met.
+// ReleaseTime and Expiry are unix seconds.
+type ConditionalPayment struct {
+	ID          string `json:"ID"`
+	Sender      string `json:"sender"`
+	Receiver    string `json:"receiver"`
+	Price       int    `json:"price"`
+	Condition   string `json:"condition"`
+	ReleaseTime int64  `json:"releaseTime"`
+	CoSigner    string `json:"coSigner"`
+	HashLock    string `json:"hashLock"`
+	Expiry      int64  `json:"expiry"`
+	Status      string `json:"status"`
+}
+
+// txTime returns the transaction timestamp, which is the same on every endorsing peer.
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
+}
+
+// CreateTimeLockedPayment escrows price from id, released to rec from releaseTime on.
+func (s *UserContract) CreateTimeLockedPayment(ctx contractapi.TransactionContextInterface, paymentID string, id string, rec string, price int, releaseTime int64, expiry int64) error {
+	if releaseTime >= expiry {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "release time must be before expiry")
+	}
+	payment := ConditionalPayment{Condition: conditionTime, ReleaseTime: releaseTime}
+	return s.createConditionalPayment(ctx, &payment, paymentID, id, rec, price, expiry)
+}
+
+// CreateCoSignedPayment escrows price from id, released to rec once coSigner approves it.
+func (s *UserContract) CreateCoSignedPayment(ctx contractapi.TransactionContextInterface, paymentID string, id string, rec string, price int, coSigner string, expiry int64) error {
+	if coSigner == "" {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "co-signer is required")
+	}
+	payment := ConditionalPayment{Condition: conditionCoSign, CoSigner: coSigner}
+	return s.createConditionalPayment(ctx, &payment, paymentID, id, rec, price, expiry)
+}
+
+// CreateHashLockedPayment escrows price from id, released to rec with the preimage of hashLock.
+// hashLock is the hex encoded SHA-256 of the preimage.
+func (s *UserContract) CreateHashLockedPayment(ctx contractapi.TransactionContextInterface, paymentID string, id string, rec string, price int, hashLock string, expiry int64) error {
+	if _, err := hex.DecodeString(hashLock); err != nil || len(hashLock) != sha256.Size*2 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "hash lock must be a hex encoded SHA-256 digest")
+	}
+	payment := ConditionalPayment{Condition: conditionHash, HashLock: hashLock}
+	return s.createConditionalPayment(ctx, &payment, paymentID, id, rec, price, expiry)
+}
+
+func (s *UserContract) createConditionalPayment(ctx contractapi.TransactionContextInterface, payment *ConditionalPayment, paymentID string, id string, rec string, price int, expiry int64) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner can escrow payments from %s", id)
+	}
+	if id == rec {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "the sender and the receiver must differ")
+	}
+	if price <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	existing, err := s.readConditionalPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the payment %s already exists", paymentID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiry <= now.Unix() {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+
+	sender, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, rec)
+	if err != nil {
+		return err
+	}
+	if freeBalance(sender) < price {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+	}
+	err = s.screenAccounts(ctx, sender, receiver)
+	if err != nil {
+		return err
+	}
+	err = s.screenTransfer(ctx, id, rec, price)
+	if err != nil {
+		return err
+	}
+	sender.Balance = sender.Balance - price
+
+	payment.ID = paymentID
+	payment.Sender = id
+	payment.Receiver = rec
+	payment.Price = price
+	payment.Expiry = expiry
+	payment.Status = paymentLocked
+
+	err = s.putConditionalPayment(ctx, payment)
+	if err != nil {
+		return err
+	}
+	senderJSON, err := json.Marshal(sender)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, senderJSON)
+}
+
+// ReleaseConditionalPayment pays an escrowed amount to the receiver once its condition holds.
+// proof is the preimage for hash locked payments and is ignored otherwise.
+func (s *UserContract) ReleaseConditionalPayment(ctx contractapi.TransactionContextInterface, paymentID string, proof string) error {
+	payment, err := s.ReadConditionalPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != paymentLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the payment %s is already %s", paymentID, payment.Status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if now.Unix() >= payment.Expiry {
+		return cbdcerr.New(cbdcerr.Rejected, "the payment %s has expired", paymentID)
+	}
+
+	switch payment.Condition {
+	case conditionTime:
+		if now.Unix() < payment.ReleaseTime {
+			return cbdcerr.New(cbdcerr.Rejected, "the payment %s is locked until %s", paymentID, time.Unix(payment.ReleaseTime, 0).UTC().Format("2006-01-02 15:04"))
+		}
+	case conditionCoSign:
+		signer, err := ctx.GetClientIdentity().GetID()
+		if err != nil {
+			return err
+		}
+		if signer != payment.CoSigner {
+			return cbdcerr.New(cbdcerr.Unauthorized, "the payment %s must be released by its co-signer", paymentID)
+		}
+	case conditionHash:
+		digest := sha256.Sum256([]byte(proof))
+		if hex.EncodeToString(digest[:]) != payment.HashLock {
+			return cbdcerr.New(cbdcerr.InvalidArgument, "invalid preimage for the payment %s", paymentID)
+		}
+	default:
+		return cbdcerr.New(cbdcerr.InvalidArgument, "unknown condition %s", payment.Condition)
+	}
+
+	receiver, err := s.ReadAccount(ctx, payment.Receiver)
+	if err != nil {
+		return err
+	}
+	rBal := receiver.Balance + payment.Price
+	if rBal > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, receiver)
+	if err != nil {
+		return err
+	}
+	receiver.Balance = rBal
+	payment.Status = paymentReleased
+
+	err = s.putConditionalPayment(ctx, payment)
+	if err != nil {
+		return err
+	}
+	receiverJSON, err := json.Marshal(receiver)
+	if err != nil {
+		return err
+	}
+
+	//기록
+	s.TransferHistory(ctx, payment.Receiver, payment.Sender, strconv.Itoa(payment.Price))
+	return ctx.GetStub().PutState(payment.Receiver, receiverJSON)
+}
+
+// ReclaimConditionalPayment returns an expired, unreleased payment to its sender.
+func (s *UserContract) ReclaimConditionalPayment(ctx contractapi.TransactionContextInterface, paymentID string) error {
+	payment, err := s.ReadConditionalPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != paymentLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the payment %s is already %s", paymentID, payment.Status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if now.Unix() < payment.Expiry {
+		return cbdcerr.New(cbdcerr.Rejected, "the payment %s has not expired yet", paymentID)
+	}
+
+	sender, err := s.ReadAccount(ctx, payment.Sender)
+	if err != nil {
+		return err
+	}
+	sender.Balance = sender.Balance + payment.Price
+	payment.Status = paymentReclaimed
+
+	err = s.putConditionalPayment(ctx, payment)
+	if err != nil {
+		return err
+	}
+	senderJSON, err := json.Marshal(sender)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(payment.Sender, senderJSON)
+}
+
+func (s *UserContract) ReadConditionalPayment(ctx contractapi.TransactionContextInterface, paymentID string) (*ConditionalPayment, error) {
+	payment, err := s.readConditionalPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the payment %s does not exist", paymentID)
+	}
+	return payment, nil
+}
+
+// ReadConditionalPaymentsUser returns the conditional payments a user sends or receives.
+func (s *UserContract) ReadConditionalPaymentsUser(ctx contractapi.TransactionContextInterface, userID string) ([]*ConditionalPayment, error) {
+	paymentJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(conditionalPaymentType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer paymentJSON.Close()
+	var payments []*ConditionalPayment
+	for paymentJSON.HasNext() {
+		queryResponse, err := paymentJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var payment ConditionalPayment
+		err = json.Unmarshal(queryResponse.Value, &payment)
+		if err != nil {
+			return nil, err
+		}
+		if payment.Sender == userID || payment.Receiver == userID {
+			payments = append(payments, &payment)
+		}
+	}
+	return payments, nil
+}
+
+func (s *UserContract) readConditionalPayment(ctx contractapi.TransactionContextInterface, paymentID string) (*ConditionalPayment, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(conditionalPaymentType, []string{paymentID})
+	if err != nil {
+		return nil, err
+	}
+	paymentJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if paymentJSON == nil {
+		return nil, nil
+	}
+	var payment ConditionalPayment
+	err = json.Unmarshal(paymentJSON, &payment)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (s *UserContract) putConditionalPayment(ctx contractapi.TransactionContextInterface, payment *ConditionalPayment) error {
+	key, err := ctx.GetStub().CreateCompositeKey(conditionalPaymentType, []string{payment.ID})
+	if err != nil {
+		return err
+	}
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, paymentJSON)
+}
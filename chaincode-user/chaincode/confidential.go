@@ -0,0 +1,317 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/confidential"
+)
+
+const (
+	auditKeyKey              = "confidentialAuditKey"
+	confidentialTransferType = "confidentialTransfer"
+
+	confidentialShield   = "shield"
+	confidentialUnshield = "unshield"
+	confidentialTransfer = "transfer"
+)
+
+// ConfidentialTransfer records a movement of confidential balance.
+// Price is only set for shield and unshield, whose amounts are public.
+// The openings of a transfer are sealed to the regulator's audit key and to the receiver.
+type ConfidentialTransfer struct {
+	ID              string `json:"ID"`
+	Kind            string `json:"kind"`
+	Sender          string `json:"sender"`
+	Receiver        string `json:"receiver"`
+	Commitment      string `json:"commitment"`
+	Price           int    `json:"price,omitempty"`
+	AuditOpening    string `json:"auditOpening,omitempty"`
+	ReceiverOpening string `json:"receiverOpening,omitempty"`
+	Date            string `json:"date"`
+}
+
+// SetAuditKey registers the PEM encoded P-256 key confidential amounts are sealed to.
+// Only the regulator can set it and it keeps the private key to open them.
+func (s *UserContract) SetAuditKey(ctx contractapi.TransactionContextInterface, publicKey string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can set the audit key")
+	}
+	if _, err := confidential.ParsePublicKey(publicKey); err != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid audit key: %v", err)
+	}
+	return ctx.GetStub().PutState(auditKeyKey, []byte(publicKey))
+}
+
+func (s *UserContract) ReadAuditKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	key, err := ctx.GetStub().GetState(auditKeyKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read world state: %v", err)
+	}
+	if key == nil {
+		return "", cbdcerr.New(cbdcerr.NotFound, "the audit key is not set")
+	}
+	return string(key), nil
+}
+
+// EnableConfidentialBalance opens a confidential balance next to the public one.
+// publicKey is the PEM encoded P-256 key senders seal amounts to for this account.
+func (s *UserContract) EnableConfidentialBalance(ctx contractapi.TransactionContextInterface, id string, publicKey string) error {
+	if !isAccountOwner(ctx, id) && !isBankOrRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s or a bank can enable its confidential balance", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if account.ConfidentialKey != "" {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the account %s already has a confidential balance", id)
+	}
+	if _, err := confidential.ParsePublicKey(publicKey); err != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid public key for the account %s: %v", id, err)
+	}
+	account.ConfidentialKey = publicKey
+	return s.putAccount(ctx, account)
+}
+
+// ShieldBalance moves price from the public balance into the confidential one.
+// The commitment grows by price*G, so the owner's blinding factor is unchanged.
+func (s *UserContract) ShieldBalance(ctx contractapi.TransactionContextInterface, id string, price int) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can shield its balance", id)
+	}
+	account, err := s.readConfidentialAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if price <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	if freeBalance(account) < price {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+	}
+	commitment, err := confidential.ParsePoint(account.Commitment)
+	if err != nil {
+		return err
+	}
+	account.Balance = account.Balance - price
+	account.Commitment = commitment.Add(confidential.CommitPublic(price)).String()
+
+	err = s.putAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	return s.recordConfidential(ctx, &ConfidentialTransfer{
+		Kind:       confidentialShield,
+		Sender:     id,
+		Receiver:   id,
+		Commitment: account.Commitment,
+		Price:      price,
+	})
+}
+
+// UnshieldBalance moves price from the confidential balance back to the public one.
+// proof is a range proof that the confidential balance left is not negative.
+func (s *UserContract) UnshieldBalance(ctx contractapi.TransactionContextInterface, id string, price int, proof string) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can unshield its balance", id)
+	}
+	account, err := s.readConfidentialAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if price <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	commitment, err := confidential.ParsePoint(account.Commitment)
+	if err != nil {
+		return err
+	}
+	remainder := commitment.Sub(confidential.CommitPublic(price))
+	err = verifyRange(proof, remainder)
+	if err != nil {
+		return cbdcerr.New(cbdcerr.Rejected, "the remaining confidential balance of %s: %v", id, err)
+	}
+
+	account.Balance = account.Balance + price
+	if account.Balance > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	account.Commitment = remainder.String()
+	err = s.putAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	return s.recordConfidential(ctx, &ConfidentialTransfer{
+		Kind:       confidentialUnshield,
+		Sender:     id,
+		Receiver:   id,
+		Commitment: account.Commitment,
+		Price:      price,
+	})
+}
+
+// TransferConfidential moves a hidden amount between confidential balances.
+// commitment commits to the amount, amountProof shows it is not negative and
+// remainderProof shows the sender's confidential balance stays non-negative.
+// Value is conserved because the same commitment is taken from the sender and
+// added to the receiver. auditOpening and receiverOpening are the amount's
+// opening sealed to the audit key and to the receiver's key; the amount and the
+// blinding factor each of them will read are checked against the commitment.
+//
+// Amounts are hidden, so only the watch list is screened, not the AML rules,
+// and the individual holding limit is only applied when balances are unshielded.
+func (s *UserContract) TransferConfidential(ctx contractapi.TransactionContextInterface, sender string, rec string, commitment string, amountProof string, remainderProof string, auditOpening string, receiverOpening string) (*ConfidentialTransfer, error) {
+	if !isAccountOwner(ctx, sender) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only %s can transfer its confidential balance", sender)
+	}
+	if sender == rec {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "cannot transfer to the same account")
+	}
+	auditKey, err := s.ReadAuditKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auditOpening == "" || receiverOpening == "" {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "the amount must be sealed to the audit key and the receiver")
+	}
+	senderAccount, err := s.readConfidentialAccount(ctx, sender)
+	if err != nil {
+		return nil, err
+	}
+	recAccount, err := s.readConfidentialAccount(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	err = s.screenAccounts(ctx, senderAccount, recAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := confidential.ParsePoint(commitment)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid commitment: %v", err)
+	}
+	err = verifyRange(amountProof, amount)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the transferred amount: %v", err)
+	}
+	err = verifySealed(auditKey, auditOpening, amount)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the opening sealed to the audit key: %v", err)
+	}
+	err = verifySealed(recAccount.ConfidentialKey, receiverOpening, amount)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the opening sealed to %s: %v", rec, err)
+	}
+	senderCommitment, err := confidential.ParsePoint(senderAccount.Commitment)
+	if err != nil {
+		return nil, err
+	}
+	remainder := senderCommitment.Sub(amount)
+	err = verifyRange(remainderProof, remainder)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the remaining confidential balance of %s: %v", sender, err)
+	}
+	recCommitment, err := confidential.ParsePoint(recAccount.Commitment)
+	if err != nil {
+		return nil, err
+	}
+
+	senderAccount.Commitment = remainder.String()
+	recAccount.Commitment = recCommitment.Add(amount).String()
+	err = s.putAccount(ctx, senderAccount)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putAccount(ctx, recAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := ConfidentialTransfer{
+		Kind:            confidentialTransfer,
+		Sender:          sender,
+		Receiver:        rec,
+		Commitment:      commitment,
+		AuditOpening:    auditOpening,
+		ReceiverOpening: receiverOpening,
+	}
+	err = s.recordConfidential(ctx, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ReadConfidentialTransfers returns the confidential movements the account took part in.
+func (s *UserContract) ReadConfidentialTransfers(ctx contractapi.TransactionContextInterface, id string) ([]*ConfidentialTransfer, error) {
+	transferJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(confidentialTransferType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer transferJSON.Close()
+	var transfers []*ConfidentialTransfer
+	for transferJSON.HasNext() {
+		queryResponse, err := transferJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var transfer ConfidentialTransfer
+		err = json.Unmarshal(queryResponse.Value, &transfer)
+		if err != nil {
+			return nil, err
+		}
+		if transfer.Sender == id || transfer.Receiver == id {
+			transfers = append(transfers, &transfer)
+		}
+	}
+	return transfers, nil
+}
+
+func (s *UserContract) readConfidentialAccount(ctx contractapi.TransactionContextInterface, id string) (*UserAccount, error) {
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.ConfidentialKey == "" {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the account %s has no confidential balance", id)
+	}
+	return account, nil
+}
+
+func (s *UserContract) putAccount(ctx contractapi.TransactionContextInterface, account *UserAccount) error {
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(account.ID, accountJSON)
+}
+
+func (s *UserContract) recordConfidential(ctx contractapi.TransactionContextInterface, transfer *ConfidentialTransfer) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	transfer.ID = ctx.GetStub().GetTxID()
+	transfer.Date = now.Format("2006-01-02 15:04")
+	return s.putRecord(ctx, confidentialTransferType, transfer.ID, transfer)
+}
+
+func verifyRange(proof string, commitment *confidential.Point) error {
+	rangeProof, err := confidential.ParseRangeProof(proof)
+	if err != nil {
+		return err
+	}
+	return rangeProof.Verify(commitment)
+}
+
+func verifySealed(publicKey string, sealed string, commitment *confidential.Point) error {
+	pub, err := confidential.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	return confidential.VerifySealed(pub, sealed, commitment)
+}
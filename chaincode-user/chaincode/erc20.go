@@ -0,0 +1,250 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+// ERC-20 interface over the UserAccount ledger, following the Fabric token-erc20 sample.
+// Accounts are named by client identity (GetClientIdentity().GetID()); each client links
+// its identity to the user account named by its userID attribute with LinkClientAccount.
+const (
+	erc20ClientType = "erc20Client"
+
+	erc20Symbol   = "CBDC"
+	erc20Decimals = 0
+)
+
+// erc20Event is the payload of the Transfer and Approval events.
+type erc20Event struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Spender string `json:"spender,omitempty"`
+	Value   int    `json:"value"`
+}
+
+func (s *UserContract) Name(ctx contractapi.TransactionContextInterface) (string, error) {
+	return CBDC_NAME + " CBDC", nil
+}
+
+func (s *UserContract) Symbol(ctx contractapi.TransactionContextInterface) (string, error) {
+	return erc20Symbol, nil
+}
+
+// Decimals is 0 as balances are whole units.
+func (s *UserContract) Decimals(ctx contractapi.TransactionContextInterface) (int, error) {
+	return erc20Decimals, nil
+}
+
+// TotalSupply returns the sum of all user account balances. Accounts are found through
+// the account index kept with their private details, so accounts whose details were never
+// migrated out of the public world state are not counted.
+func (s *UserContract) TotalSupply(ctx contractapi.TransactionContextInterface) (int, error) {
+	indexJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(accountIndexType, []string{})
+	if err != nil {
+		return 0, err
+	}
+	defer indexJSON.Close()
+	total := 0
+	for indexJSON.HasNext() {
+		queryResponse, err := indexJSON.Next()
+		if err != nil {
+			return 0, err
+		}
+		_, keys, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, err
+		}
+		accountJSON, err := ctx.GetStub().GetState(keys[0])
+		if err != nil {
+			return 0, fmt.Errorf("failed to read world state: %v", err)
+		}
+		if accountJSON == nil {
+			continue
+		}
+		var account UserAccount
+		err = json.Unmarshal(accountJSON, &account)
+		if err != nil {
+			return 0, err
+		}
+		total = total + account.Balance
+	}
+	return total, nil
+}
+
+// LinkClientAccount links the caller's client identity to the user account it owns
+// and returns the client identity other ERC-20 calls name it by.
+func (s *UserContract) LinkClientAccount(ctx contractapi.TransactionContextInterface) (string, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", err
+	}
+	userID, found, err := ctx.GetClientIdentity().GetAttributeValue(accountAttr)
+	if err != nil || !found {
+		return "", cbdcerr.New(cbdcerr.Unauthorized, "the client is not enrolled with a %s attribute", accountAttr)
+	}
+	if _, err := s.ReadAccount(ctx, userID); err != nil {
+		return "", err
+	}
+	return clientID, s.putRecord(ctx, erc20ClientType, clientID, userID)
+}
+
+// ClientAccountID returns the caller's client identity.
+func (s *UserContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
+	return ctx.GetClientIdentity().GetID()
+}
+
+// ClientAccountBalance returns the balance of the caller's linked account.
+func (s *UserContract) ClientAccountBalance(ctx contractapi.TransactionContextInterface) (int, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, err
+	}
+	return s.BalanceOf(ctx, clientID)
+}
+
+// BalanceOf returns the balance of the account linked to the client identity account.
+func (s *UserContract) BalanceOf(ctx contractapi.TransactionContextInterface, account string) (int, error) {
+	userAccount, err := s.clientAccount(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	return userAccount.Balance, nil
+}
+
+// Transfer sends value from the caller's account to the account linked to recipient.
+func (s *UserContract) Transfer(ctx contractapi.TransactionContextInterface, recipient string, value int) error {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return err
+	}
+	return s.erc20Transfer(ctx, clientID, recipient, value)
+}
+
+// Approve lets spender transfer up to value from the caller's account, replacing any previous allowance.
+// ApproveWithLimits also sets an expiry and a per-period limit.
+func (s *UserContract) Approve(ctx contractapi.TransactionContextInterface, spender string, value int) error {
+	return s.ApproveWithLimits(ctx, spender, value, 0, 0, 0)
+}
+
+// Allowance returns what spender can transfer from owner's account right now.
+func (s *UserContract) Allowance(ctx contractapi.TransactionContextInterface, owner string, spender string) (int, error) {
+	allowance, err := s.readAllowance(ctx, owner, spender)
+	if err != nil {
+		return 0, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return allowance.available(now.Unix()), nil
+}
+
+// TransferFrom lets the caller spend value of from's allowance, sending it to to.
+// The allowance must not have expired and value must fit in its current period.
+func (s *UserContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
+	spender, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return err
+	}
+	err = s.spendAllowance(ctx, from, spender, value)
+	if err != nil {
+		return err
+	}
+	return s.erc20Transfer(ctx, from, to, value)
+}
+
+// erc20Transfer moves value between the accounts linked to two client identities
+// with the same checks and fees as the other user transfers, and emits a Transfer event.
+func (s *UserContract) erc20Transfer(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
+	if value <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "transfer amount must be positive")
+	}
+	sender, err := s.clientAccount(ctx, from)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.clientAccount(ctx, to)
+	if err != nil {
+		return err
+	}
+	if sender.ID == receiver.ID {
+		return cbdcerr.New(cbdcerr.Rejected, "cannot transfer to the same account")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 용도 지정 잔액은 허용된 업종에서만 사용
+	free := freeBalance(sender)
+	if !spendTagged(sender, receiver.Category, value, now.Unix()) {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", sender.ID)
+	}
+	rBal := receiver.Balance + value
+	if rBal > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, sender, receiver)
+	if err != nil {
+		return err
+	}
+	err = s.screenTransfer(ctx, sender.ID, receiver.ID, value)
+	if err != nil {
+		return err
+	}
+	txType := feeP2P
+	if receiver.Category != "" {
+		txType = feeMerchant
+	}
+	quote, err := s.quoteFee(ctx, txType, sender.ID, value)
+	if err != nil {
+		return err
+	}
+	sender.Balance = sender.Balance - value - quote.Fee
+	if freeBalance(sender) < 0 {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", sender.ID)
+	}
+	err = s.payFee(ctx, quote)
+	if err != nil {
+		return err
+	}
+	// 만료형 잔액은 먼저 발행된 것부터 사용
+	spendLots(sender, free-freeBalance(sender))
+	receiver.Balance = rBal
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	err = s.putAccount(ctx, receiver)
+	if err != nil {
+		return err
+	}
+
+	//기록
+	s.transferHistoryFee(ctx, receiver.ID, sender.ID, strconv.Itoa(value), quote.Fee)
+	eventJSON, err := json.Marshal(erc20Event{From: from, To: to, Value: value})
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, "Transfer", eventJSON)
+}
+
+// clientAccount returns the user account linked to a client identity.
+func (s *UserContract) clientAccount(ctx contractapi.TransactionContextInterface, clientID string) (*UserAccount, error) {
+	var userID string
+	found, err := s.getRecord(ctx, erc20ClientType, clientID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the client %s has no linked account", clientID)
+	}
+	return s.ReadAccount(ctx, userID)
+}
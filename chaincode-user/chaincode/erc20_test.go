@@ -0,0 +1,78 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// p2pFee is the regulatory chaincode charging fee on p2p payments, paid to Bank1.
+func p2pFee(fee string) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] == "QuoteFee" && args[1] == "p2p" {
+			return shim.Success([]byte(`{"txType":"p2p","fee":` + fee + `,"payee":"Bank1"}`))
+		}
+		return screen(args)
+	}
+}
+
+func TestERC20Transfer(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, nil)
+	for _, id := range []string{"User1", "User2"} {
+		l.transient = map[string][]byte{"account": []byte(`{"name":"` + id + `"}`)}
+		require.NoError(t, l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.CreateAccount(ctx, id) }))
+	}
+	l.transient = nil
+	account := l.account("User1")
+	account.Balance = 100
+	account.Lots = []*chaincode.BalanceLot{{Issuer: "Central Bank", Amount: 100, Expiry: l.now.Unix() + 86400}}
+	l.put("User1", account)
+	l.put("1", chaincode.AccountHistory{ID: "1", Receiver: "User1", Sender: "Bank1", Price: "100"})
+	l.peer("regulatorychaincode", "regulatory-channel", p2pFee("2"))
+
+	link := func(c client) (string, error) {
+		var clientID string
+		err := l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			clientID, err = s.LinkClientAccount(ctx)
+			return err
+		})
+		return clientID, err
+	}
+	_, err := link(operator("Bank1"))
+	requireCode(t, err, cbdcerr.Unauthorized, "the client is not enrolled with a userID attribute")
+	_, err = link(owner("User1"))
+	require.NoError(t, err)
+	recipient, err := link(owner("User2"))
+	require.NoError(t, err)
+	transfer := func(to string, value int) error {
+		return l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error { return s.Transfer(ctx, to, value) })
+	}
+
+	// 수수료는 송금액과 함께 자유 잔액에서 빠지고 만료형 잔액부터 사용
+	require.NoError(t, transfer(recipient, 50))
+	require.Equal(t, "Transfer", l.lastEvent().EventName)
+	account = l.account("User1")
+	require.Equal(t, 48, account.Balance)
+	require.Equal(t, 48, account.Lots[0].Amount)
+	require.Equal(t, 50, l.balance("User2"))
+	require.Equal(t, 2, receivable(l, "Bank1"))
+
+	requireCode(t, transfer(recipient, 47), cbdcerr.InsufficientFunds, "Lack of balance User1's Account")
+	self, _ := owner("User1").GetID()
+	requireCode(t, transfer(self, 10), cbdcerr.Rejected, "cannot transfer to the same account")
+
+	var supply int
+	require.NoError(t, l.tx(owner("User2"), func(ctx contractapi.TransactionContextInterface) error {
+		supply, err = s.TotalSupply(ctx)
+		return err
+	}))
+	require.Equal(t, 98, supply)
+}
@@ -0,0 +1,74 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// txEvent is one event emitted by a transaction.
+type txEvent struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// txEvents collects the events emitted by each running transaction, by tx ID.
+var txEvents = struct {
+	sync.Mutex
+	m map[string][]*txEvent
+}{m: make(map[string][]*txEvent)}
+
+// emitEvent adds an event to the transaction. Fabric keeps a single event per transaction,
+// the last one set, so every emit sets the events of the transaction so far: a lone event
+// keeps its name and payload, several are set under their names joined by commas with
+// a JSON list of txEvent as payload.
+func emitEvent(ctx contractapi.TransactionContextInterface, name string, payload []byte) error {
+	txID := ctx.GetStub().GetTxID()
+	txEvents.Lock()
+	events := append(txEvents.m[txID], &txEvent{Name: name, Payload: payload})
+	txEvents.m[txID] = events
+	txEvents.Unlock()
+
+	if len(events) == 1 {
+		return ctx.GetStub().SetEvent(name, payload)
+	}
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Name
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(strings.Join(names, ","), eventsJSON)
+}
+
+type eventsChaincode struct {
+	cc shim.Chaincode
+}
+
+// Events wraps the chaincode to drop the events collected by emitEvent, and what oweBank
+// collected, once each transaction has run.
+func Events(cc shim.Chaincode) shim.Chaincode {
+	return &eventsChaincode{cc: cc}
+}
+
+func (e *eventsChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return e.cc.Init(stub)
+}
+
+func (e *eventsChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	defer func() {
+		txEvents.Lock()
+		delete(txEvents.m, stub.GetTxID())
+		txEvents.Unlock()
+		txReceivables.Lock()
+		delete(txReceivables.m, stub.GetTxID())
+		txReceivables.Unlock()
+	}()
+	return e.cc.Invoke(stub)
+}
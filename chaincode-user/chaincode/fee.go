@@ -0,0 +1,49 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Transaction types of user payments in the regulatory fee schedules.
+const (
+	feeMerchant = "merchant"
+	feeP2P      = "p2p"
+)
+
+// feeQuote is the fee the regulatory chaincode charges on a user payment.
+type feeQuote struct {
+	TxType string `json:"txType"`
+	Payer  string `json:"payer"`
+	Tier   string `json:"tier"`
+	Amount int    `json:"amount"`
+	Fee    int    `json:"fee"`
+	Payee  string `json:"payee,omitempty"`
+	Waived bool   `json:"waived,omitempty"`
+}
+
+// quoteFee returns the fee of a payment of price by payer from the regulatory fee schedules.
+// The caller debits the payer by the fee and records it with payFee.
+func (s *UserContract) quoteFee(ctx contractapi.TransactionContextInterface, txType string, payer string, price int) (*feeQuote, error) {
+	payload, err := queryRegulatory(ctx, "QuoteFee", txType, payer, strconv.Itoa(price))
+	if err != nil {
+		return nil, err
+	}
+	var quote feeQuote
+	err = json.Unmarshal(payload, &quote)
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// payFee adds the fee of quote to what the user channel owes its payee, which the
+// regulatory chaincode credits with SettleBankReceivable.
+func (s *UserContract) payFee(ctx contractapi.TransactionContextInterface, quote *feeQuote) error {
+	if quote.Fee == 0 {
+		return nil
+	}
+	return s.oweBank(ctx, quote.Payee, quote.Fee)
+}
@@ -0,0 +1,219 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	holdType = "hold"
+
+	holdOpen     = "open"
+	holdReleased = "released"
+	holdReversed = "reversed"
+
+	disputeReversed  = "reversed"
+	disputeDismissed = "dismissed"
+)
+
+// Hold freezes part of a receiver's balance while a dispute over a history entry is open.
+// The claimant, the sender of the entry, places it and opens the dispute case on the
+// regulatory channel with FileDispute; the regulator can do both for it. Once the
+// regulator has resolved the case there, it releases the hold.
+type Hold struct {
+	CaseID    string `json:"caseID"`
+	HistoryID string `json:"historyID"`
+	AccountID string `json:"accountID"`
+	Claimant  string `json:"claimant"`
+	Price     int    `json:"price"`
+	Status    string `json:"status"`
+}
+
+// resolvedDispute is the dispute case read from the regulatory chaincode.
+type resolvedDispute struct {
+	ID     string `json:"ID"`
+	Status string `json:"status"`
+}
+
+// PlaceHold holds price in the receiver's account for the dispute caseID over a history entry.
+// The sender of the entry or the regulator can place it.
+func (s *UserContract) PlaceHold(ctx contractapi.TransactionContextInterface, caseID string, historyID string, price int) (*Hold, error) {
+	found, err := s.getRecord(ctx, holdType, caseID, &Hold{})
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, cbdcerr.New(cbdcerr.AlreadyExists, "the hold %s already exists", caseID)
+	}
+	history, err := s.readHistory(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if !isRegulator(ctx) && !isAccountOwner(ctx, history.Sender) && !isBankOperator(ctx, history.Sender) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only %s or the regulator can place the hold %s", history.Sender, caseID)
+	}
+
+	original, e := strconv.Atoi(history.Price)
+	if e != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid price in the history %s", historyID)
+	}
+	refunded, err := s.RefundedAmount(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if price <= 0 || price > original-refunded {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "disputed price must be between 1 and %d", original-refunded)
+	}
+
+	account, err := s.ReadAccount(ctx, history.Receiver)
+	if err != nil {
+		return nil, err
+	}
+	if freeBalance(account) < price {
+		return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", account.ID)
+	}
+	account.Held = account.Held + price
+
+	hold := Hold{
+		CaseID:    caseID,
+		HistoryID: historyID,
+		AccountID: account.ID,
+		Claimant:  history.Sender,
+		Price:     price,
+		Status:    holdOpen,
+	}
+	err = s.putRecord(ctx, holdType, caseID, hold)
+	if err != nil {
+		return nil, err
+	}
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(account.ID, accountJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseHold closes the hold of a dispute the regulatory chaincode has resolved.
+// When the dispute was reversed the held amount goes back to the claimant and counts
+// as a refund of the history entry; a claimant bank is owed it and credited with
+// SettleBankReceivable. Only the regulator can release holds.
+func (s *UserContract) ReleaseHold(ctx contractapi.TransactionContextInterface, caseID string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can release the hold %s", caseID)
+	}
+	var hold Hold
+	found, err := s.getRecord(ctx, holdType, caseID, &hold)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return cbdcerr.New(cbdcerr.NotFound, "the hold %s does not exist", caseID)
+	}
+	if hold.Status != holdOpen {
+		return cbdcerr.New(cbdcerr.Rejected, "the hold %s is already %s", caseID, hold.Status)
+	}
+
+	payload, err := queryRegulatory(ctx, "ReadDispute", caseID)
+	if err != nil {
+		return err
+	}
+	var d resolvedDispute
+	err = json.Unmarshal(payload, &d)
+	if err != nil {
+		return err
+	}
+	var reverse bool
+	switch d.Status {
+	case disputeReversed:
+		reverse = true
+	case disputeDismissed:
+		reverse = false
+	default:
+		return cbdcerr.New(cbdcerr.Rejected, "the dispute %s is still %s", caseID, d.Status)
+	}
+
+	account, err := s.ReadAccount(ctx, hold.AccountID)
+	if err != nil {
+		return err
+	}
+	account.Held = account.Held - hold.Price
+	hold.Status = holdReleased
+
+	if reverse {
+		account.Balance = account.Balance - hold.Price
+		hold.Status = holdReversed
+
+		claimant, err := s.ReadAccount(ctx, hold.Claimant)
+		if err != nil && cbdcerr.CodeOf(err) != cbdcerr.NotFound {
+			return err
+		}
+		if err != nil {
+			// 원거래 송신자가 은행이면 은행 계좌로 반환
+			payload, err := queryRegulatory(ctx, "AccountExist", hold.Claimant)
+			if err != nil {
+				return err
+			}
+			if string(payload) != "true" {
+				return cbdcerr.New(cbdcerr.NotFound, "the claimant %s of the hold %s does not exist", hold.Claimant, caseID)
+			}
+			err = s.oweBank(ctx, hold.Claimant, hold.Price)
+			if err != nil {
+				return err
+			}
+		} else {
+			claimant.Balance = claimant.Balance + hold.Price
+			if claimant.Balance > MAX_VAL {
+				return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+			}
+			claimantJSON, err := json.Marshal(claimant)
+			if err != nil {
+				return err
+			}
+			err = ctx.GetStub().PutState(claimant.ID, claimantJSON)
+			if err != nil {
+				return err
+			}
+		}
+
+		refunded, err := s.RefundedAmount(ctx, hold.HistoryID)
+		if err != nil {
+			return err
+		}
+		err = s.putRecord(ctx, refundTotalType, hold.HistoryID, refunded+hold.Price)
+		if err != nil {
+			return err
+		}
+
+		//기록
+		s.TransferHistory(ctx, hold.Claimant, hold.AccountID, strconv.Itoa(hold.Price))
+	}
+
+	err = s.putRecord(ctx, holdType, caseID, hold)
+	if err != nil {
+		return err
+	}
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(account.ID, accountJSON)
+}
+
+func (s *UserContract) ReadHold(ctx contractapi.TransactionContextInterface, caseID string) (*Hold, error) {
+	var hold Hold
+	found, err := s.getRecord(ctx, holdType, caseID, &hold)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the hold %s does not exist", caseID)
+	}
+	return &hold, nil
+}
@@ -0,0 +1,80 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// disputes is the regulatory chaincode with the disputes at their status.
+func disputes(status map[string]string) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] != "ReadDispute" {
+			return screen(args)
+		}
+		return shim.Success([]byte(`{"ID":"` + args[1] + `","status":"` + status[args[1]] + `"}`))
+	}
+}
+
+func placeHold(l *ledger, c client, caseID string, historyID string, price int) error {
+	s := chaincode.UserContract{}
+	return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.PlaceHold(ctx, caseID, historyID, price)
+		return err
+	})
+}
+
+func releaseHold(l *ledger, c client, caseID string) error {
+	s := chaincode.UserContract{}
+	return l.tx(c, func(ctx contractapi.TransactionContextInterface) error { return s.ReleaseHold(ctx, caseID) })
+}
+
+func TestHoldReversed(t *testing.T) {
+	l := newLedger(t, map[string]int{"User1": 100, "User2": 300})
+	l.put("1", chaincode.AccountHistory{ID: "1", Receiver: "User2", Sender: "User1", Price: "200"})
+	status := map[string]string{"case1": "open"}
+	l.peer("regulatorychaincode", "regulatory-channel", disputes(status))
+
+	requireCode(t, placeHold(l, owner("User2"), "case1", "1", 150), cbdcerr.Unauthorized, "only User1 or the regulator can place the hold case1")
+	require.NoError(t, placeHold(l, owner("User1"), "case1", "1", 150))
+	require.Equal(t, 150, l.account("User2").Held)
+
+	// 보류된 금액은 환불에도 쓸 수 없음
+	requireCode(t, refund(l, owner("User2"), "1", 200), cbdcerr.InsufficientFunds, "Lack of balance User2's Account")
+
+	requireCode(t, releaseHold(l, regulator, "case1"), cbdcerr.Rejected, "the dispute case1 is still open")
+	status["case1"] = "reversed"
+	requireCode(t, releaseHold(l, owner("User1"), "case1"), cbdcerr.Unauthorized, "only the regulator can release the hold case1")
+	require.NoError(t, releaseHold(l, regulator, "case1"))
+
+	account := l.account("User2")
+	require.Equal(t, 150, account.Balance)
+	require.Equal(t, 0, account.Held)
+	require.Equal(t, 250, l.balance("User1"))
+	requireCode(t, refund(l, owner("User2"), "1", 60), cbdcerr.LimitExceeded, "refund of 60 exceeds the remaining 50 of the history 1")
+	requireCode(t, releaseHold(l, regulator, "case1"), cbdcerr.Rejected, "the hold case1 is already reversed")
+}
+
+func TestHoldForBank(t *testing.T) {
+	l := newLedger(t, map[string]int{"User2": 300})
+	l.put("1", chaincode.AccountHistory{ID: "1", Receiver: "User2", Sender: "Bank1", Price: "200"})
+	status := map[string]string{"case1": "reversed", "case2": "dismissed"}
+	l.peer("regulatorychaincode", "regulatory-channel", disputes(status))
+
+	require.NoError(t, placeHold(l, operator("Bank1"), "case1", "1", 100))
+	require.NoError(t, placeHold(l, regulator, "case2", "1", 50))
+	require.Equal(t, 150, l.account("User2").Held)
+
+	require.NoError(t, releaseHold(l, regulator, "case1"))
+	require.NoError(t, releaseHold(l, regulator, "case2"))
+	account := l.account("User2")
+	require.Equal(t, 200, account.Balance)
+	require.Equal(t, 0, account.Held)
+	require.Equal(t, 100, receivable(l, "Bank1"))
+}
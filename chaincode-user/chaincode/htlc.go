@@ -0,0 +1,221 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	htlcType = "htlc"
+
+	htlcLocked   = "locked"
+	htlcClaimed  = "claimed"
+	htlcRefunded = "refunded"
+)
+
+// HTLC is an amount locked in a user account under a hash lock, for atomic settlement
+// against an asset on another ledger. The receiver gets it with the preimage of HashLock
+// before Timeout (unix seconds); after Timeout it is released back to the sender.
+// While locked it stays in the sender's balance but is not free to spend.
+type HTLC struct {
+	ID       string `json:"ID"`
+	Sender   string `json:"sender"`
+	Receiver string `json:"receiver"`
+	Amount   int    `json:"amount"`
+	HashLock string `json:"hashLock"`
+	Preimage string `json:"preimage,omitempty"`
+	Timeout  int64  `json:"timeout"`
+	Status   string `json:"status"`
+	Date     string `json:"date"`
+}
+
+// LockHTLC locks amount in the account id for rec under hashLock, the hex encoded SHA-256
+// of a preimage, until timeout.
+func (s *UserContract) LockHTLC(ctx contractapi.TransactionContextInterface, htlcID string, id string, rec string, amount int, hashLock string, timeout int64) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner can lock %s's balance", id)
+	}
+	if _, err := hex.DecodeString(hashLock); err != nil || len(hashLock) != sha256.Size*2 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "hash lock must be a hex encoded SHA-256 digest")
+	}
+	if amount <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "amount must be positive")
+	}
+	if rec == id {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "an HTLC cannot pay its own sender")
+	}
+	found, err := s.getRecord(ctx, htlcType, htlcID, &HTLC{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the HTLC %s already exists", htlcID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if timeout <= now.Unix() {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "timeout must be in the future")
+	}
+
+	sender, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, rec)
+	if err != nil {
+		return err
+	}
+	if freeBalance(sender) < amount {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+	}
+	err = s.screenAccounts(ctx, sender, receiver)
+	if err != nil {
+		return err
+	}
+	err = s.screenTransfer(ctx, id, rec, amount)
+	if err != nil {
+		return err
+	}
+	sender.Locked = sender.Locked + amount
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	htlc := HTLC{
+		ID:       htlcID,
+		Sender:   id,
+		Receiver: rec,
+		Amount:   amount,
+		HashLock: hashLock,
+		Timeout:  timeout,
+	}
+	return s.moveHTLC(ctx, &htlc, htlcLocked)
+}
+
+// ClaimHTLC pays a locked HTLC to its receiver given the preimage of its hash lock.
+// Anyone holding the preimage can submit it; the preimage is published in the HTLC event
+// for the counterparty on the other ledger.
+func (s *UserContract) ClaimHTLC(ctx contractapi.TransactionContextInterface, htlcID string, preimage string) error {
+	htlc, err := s.ReadHTLC(ctx, htlcID)
+	if err != nil {
+		return err
+	}
+	if htlc.Status != htlcLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s is already %s", htlcID, htlc.Status)
+	}
+	digest := sha256.Sum256([]byte(preimage))
+	if hex.EncodeToString(digest[:]) != htlc.HashLock {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid preimage for the HTLC %s", htlcID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if now.Unix() >= htlc.Timeout {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s has timed out", htlcID)
+	}
+
+	sender, err := s.ReadAccount(ctx, htlc.Sender)
+	if err != nil {
+		return err
+	}
+	receiver, err := s.ReadAccount(ctx, htlc.Receiver)
+	if err != nil {
+		return err
+	}
+	rBal := receiver.Balance + htlc.Amount
+	if rBal > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, receiver)
+	if err != nil {
+		return err
+	}
+	sender.Locked = sender.Locked - htlc.Amount
+	sender.Balance = sender.Balance - htlc.Amount
+	receiver.Balance = rBal
+	htlc.Preimage = preimage
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	err = s.putAccount(ctx, receiver)
+	if err != nil {
+		return err
+	}
+
+	//기록
+	s.TransferHistory(ctx, htlc.Receiver, htlc.Sender, strconv.Itoa(htlc.Amount))
+	return s.moveHTLC(ctx, htlc, htlcClaimed)
+}
+
+// RefundHTLC releases a locked HTLC back to its sender once it has timed out.
+func (s *UserContract) RefundHTLC(ctx contractapi.TransactionContextInterface, htlcID string) error {
+	htlc, err := s.ReadHTLC(ctx, htlcID)
+	if err != nil {
+		return err
+	}
+	if htlc.Status != htlcLocked {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s is already %s", htlcID, htlc.Status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if now.Unix() < htlc.Timeout {
+		return cbdcerr.New(cbdcerr.Rejected, "the HTLC %s has not timed out yet", htlcID)
+	}
+
+	sender, err := s.ReadAccount(ctx, htlc.Sender)
+	if err != nil {
+		return err
+	}
+	sender.Locked = sender.Locked - htlc.Amount
+
+	err = s.putAccount(ctx, sender)
+	if err != nil {
+		return err
+	}
+	return s.moveHTLC(ctx, htlc, htlcRefunded)
+}
+
+func (s *UserContract) ReadHTLC(ctx contractapi.TransactionContextInterface, htlcID string) (*HTLC, error) {
+	var htlc HTLC
+	found, err := s.getRecord(ctx, htlcType, htlcID, &htlc)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the HTLC %s does not exist", htlcID)
+	}
+	return &htlc, nil
+}
+
+// moveHTLC sets the status of the HTLC, stores it and emits it as an event.
+func (s *UserContract) moveHTLC(ctx contractapi.TransactionContextInterface, htlc *HTLC, status string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	htlc.Status = status
+	htlc.Date = now.Format("2006-01-02 15:04")
+
+	err = s.putRecord(ctx, htlcType, htlc.ID, htlc)
+	if err != nil {
+		return err
+	}
+	htlcJSON, err := json.Marshal(htlc)
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, "HTLC", htlcJSON)
+}
@@ -0,0 +1,156 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	idempotencyType = "idempotency"
+
+	// idempotencyTransientKey is the transient map entry carrying the client's idempotency key.
+	idempotencyTransientKey = "idempotencyKey"
+)
+
+// IdempotencyRecord is the outcome of a request submitted with an idempotency key.
+// Request is the SHA-256 of the function name and arguments.
+type IdempotencyRecord struct {
+	Key      string `json:"key"`
+	TxID     string `json:"txID"`
+	Function string `json:"function"`
+	Request  string `json:"request"`
+	Status   int32  `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Payload  string `json:"payload"`
+	Date     string `json:"date"`
+}
+
+type idempotentChaincode struct {
+	cc shim.Chaincode
+}
+
+// Idempotent wraps the contract chaincode so that any transaction submitted with an
+// idempotency key in its transient map runs at most once per client. The first successful
+// or refused run stores its tx ID and response under the key; a retry with the same key and
+// request returns that response without running again. Failed runs are not committed, so
+// they can be retried with the same key.
+func Idempotent(cc shim.Chaincode) shim.Chaincode {
+	return &idempotentChaincode{cc: cc}
+}
+
+func (i *idempotentChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return i.cc.Init(stub)
+}
+
+func (i *idempotentChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed to read transient map: %v", err))
+	}
+	key := string(transient[idempotencyTransientKey])
+	if key == "" {
+		return i.cc.Invoke(stub)
+	}
+
+	clientID, err := cid.GetID(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	stateKey, err := stub.CreateCompositeKey(idempotencyType, []string{clientID, key})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	recordJSON, err := stub.GetState(stateKey)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed to read world state: %v", err))
+	}
+	request := requestHash(stub.GetArgs())
+	if recordJSON != nil {
+		var record IdempotencyRecord
+		err = json.Unmarshal(recordJSON, &record)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if record.Request != request {
+			return shim.Error(cbdcerr.New(cbdcerr.AlreadyExists, "the idempotency key %s was already used for another request in %s", key, record.TxID).Error())
+		}
+		return peer.Response{Status: record.Status, Message: record.Message, Payload: []byte(record.Payload)}
+	}
+
+	response := i.cc.Invoke(stub)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return response
+	}
+
+	function, _ := stub.GetFunctionAndParameters()
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	record := IdempotencyRecord{
+		Key:      key,
+		TxID:     stub.GetTxID(),
+		Function: function,
+		Request:  request,
+		Status:   response.Status,
+		Message:  response.Message,
+		Payload:  string(response.Payload),
+		Date:     time.Unix(ts.Seconds, int64(ts.Nanos)).UTC().Format("2006-01-02 15:04"),
+	}
+	recordJSON, err = json.Marshal(record)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(stateKey, recordJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return response
+}
+
+// requestHash hashes the function name and arguments, each prefixed with its length.
+func requestHash(args [][]byte) string {
+	h := sha256.New()
+	for _, arg := range args {
+		length := make([]byte, 8)
+		binary.BigEndian.PutUint64(length, uint64(len(arg)))
+		h.Write(length)
+		h.Write(arg)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadIdempotencyRecord returns the outcome stored for one of the caller's idempotency keys.
+func (s *UserContract) ReadIdempotencyRecord(ctx contractapi.TransactionContextInterface, key string) (*IdempotencyRecord, error) {
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, err
+	}
+	stateKey, err := ctx.GetStub().CreateCompositeKey(idempotencyType, []string{clientID, key})
+	if err != nil {
+		return nil, err
+	}
+	recordJSON, err := ctx.GetStub().GetState(stateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the idempotency key %s has not been used", key)
+	}
+	var record IdempotencyRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
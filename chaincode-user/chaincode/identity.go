@@ -0,0 +1,50 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	centralbankMSP    = "centralbankOrg"
+	commercialbankMSP = "commercialbankOrg"
+
+	// accountAttr is the Fabric CA enrollment attribute naming the account a client owns.
+	accountAttr = "userID"
+
+	// bankAttr is the Fabric CA enrollment attribute naming the bank account a client operates.
+	bankAttr = "bankID"
+
+	// schedulerAttr is the Fabric CA enrollment attribute, set to "true", of the scheduler
+	// that runs standing orders.
+	schedulerAttr = "scheduler"
+)
+
+// isAccountOwner reports whether the client is enrolled as the owner of account id.
+func isAccountOwner(ctx contractapi.TransactionContextInterface, id string) bool {
+	owner, found, err := ctx.GetClientIdentity().GetAttributeValue(accountAttr)
+	return err == nil && found && owner == id
+}
+
+// isBankOperator reports whether the client is enrolled as an operator of bank id.
+func isBankOperator(ctx contractapi.TransactionContextInterface, id string) bool {
+	bank, found, err := ctx.GetClientIdentity().GetAttributeValue(bankAttr)
+	return err == nil && found && bank == id
+}
+
+// isBankOrRegulator reports whether the client belongs to a bank or the central bank.
+func isBankOrRegulator(ctx contractapi.TransactionContextInterface) bool {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	return err == nil && (mspID == centralbankMSP || mspID == commercialbankMSP)
+}
+
+// isRegulator reports whether the client belongs to the central bank.
+func isRegulator(ctx contractapi.TransactionContextInterface) bool {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	return err == nil && mspID == centralbankMSP
+}
+
+// isScheduler reports whether the client is enrolled as the standing order scheduler.
+func isScheduler(ctx contractapi.TransactionContextInterface) bool {
+	value, found, err := ctx.GetClientIdentity().GetAttributeValue(schedulerAttr)
+	return err == nil && found && value == "true"
+}
@@ -0,0 +1,226 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	rateScheduleKey   = "rateSchedule"
+	interestTotalsKey = "interestTotals"
+
+	// microUnits is the number of accrual units in one unit of CBDC.
+	// Interest accrues in micro-units so that short periods between touches are not rounded away.
+	microUnits = 1000000
+
+	secondsPerYear = 365 * 24 * 60 * 60
+)
+
+// RateTier applies Rate, in annual basis points, to the part of a balance above Threshold
+// up to the threshold of the next tier. A negative rate is a holding fee.
+type RateTier struct {
+	Threshold int `json:"threshold"`
+	Rate      int `json:"rate"`
+}
+
+// rateSchedule is taken from the central bank chaincode. Since is when it took effect, in unix seconds.
+type rateSchedule struct {
+	Tiers []*RateTier `json:"tiers"`
+	Since int64       `json:"since"`
+	Date  string      `json:"date"`
+}
+
+// interestPosting is the outcome of one posting run, or of all of them for the totals
+// the central bank chaincode reconciles the total issued with.
+// Paid is the interest credited and Charged the holding fees debited.
+type interestPosting struct {
+	Accounts int    `json:"accounts"`
+	Paid     int    `json:"paid"`
+	Charged  int    `json:"charged"`
+	Date     string `json:"date"`
+}
+
+// SetRateSchedule takes the rates the central bank set with SetRateSchedule on its channel.
+func (s *UserContract) SetRateSchedule(ctx contractapi.TransactionContextInterface) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can set interest rates")
+	}
+	payload, err := queryCentralBank(ctx, "ReadRateSchedule")
+	if err != nil {
+		return err
+	}
+	var rates rateSchedule
+	err = json.Unmarshal(payload, &rates)
+	if err != nil {
+		return err
+	}
+	scheduleJSON, err := json.Marshal(rates)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(rateScheduleKey, scheduleJSON)
+}
+
+// ReadRateSchedule returns the current rate schedule. Before one is set no interest accrues.
+func (s *UserContract) ReadRateSchedule(ctx contractapi.TransactionContextInterface) (*rateSchedule, error) {
+	scheduleJSON, err := ctx.GetStub().GetState(rateScheduleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	var rates rateSchedule
+	if scheduleJSON == nil {
+		return &rates, nil
+	}
+	err = json.Unmarshal(scheduleJSON, &rates)
+	if err != nil {
+		return nil, err
+	}
+	return &rates, nil
+}
+
+// PostAccountInterest posts the whole units of interest accrued on the accounts to their
+// balances, and records each posting in the history and in the interest totals, which the
+// central bank chaincode adjusts the total issued by with ReconcileInterest.
+// Interest that would take a balance over MAX_VAL, or fees larger than the balance,
+// stay accrued until a later posting.
+func (s *UserContract) PostAccountInterest(ctx contractapi.TransactionContextInterface, accountIDs []string) (*interestPosting, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can post interest")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := newAccountCache()
+	posting := interestPosting{Date: now.Format("2006-01-02 15:04")}
+	var history []*AccountHistory
+	for _, id := range accountIDs {
+		if _, ok := accounts.accounts[id]; ok {
+			continue
+		}
+		account, err := accounts.get(ctx, s, id)
+		if err != nil {
+			return nil, err
+		}
+		posting.Accounts = posting.Accounts + 1
+
+		units := int(account.AccruedInterest / microUnits)
+		switch {
+		case units > 0:
+			if account.Balance+units > MAX_VAL {
+				units = MAX_VAL - account.Balance
+			}
+			if units <= 0 {
+				continue
+			}
+			posting.Paid = posting.Paid + units
+			history = append(history, &AccountHistory{Receiver: id, Price: strconv.Itoa(units), Sender: "interest"})
+		case units < 0:
+			if account.Balance+units < 0 {
+				units = -account.Balance
+			}
+			if units == 0 {
+				continue
+			}
+			posting.Charged = posting.Charged - units
+			history = append(history, &AccountHistory{Receiver: "holdingFee", Price: strconv.Itoa(-units), Sender: id})
+		default:
+			continue
+		}
+		account.Balance = account.Balance + units
+		account.AccruedInterest = account.AccruedInterest - int64(units)*microUnits
+	}
+
+	err = accounts.flush(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	err = s.transferHistories(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.ReadInterestTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totals.Accounts = totals.Accounts + posting.Accounts
+	totals.Paid = totals.Paid + posting.Paid
+	totals.Charged = totals.Charged + posting.Charged
+	totals.Date = posting.Date
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(interestTotalsKey, totalsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &posting, nil
+}
+
+// ReadInterestTotals returns the interest posted by all runs of PostAccountInterest.
+func (s *UserContract) ReadInterestTotals(ctx contractapi.TransactionContextInterface) (*interestPosting, error) {
+	totalsJSON, err := ctx.GetStub().GetState(interestTotalsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	var totals interestPosting
+	if totalsJSON == nil {
+		return &totals, nil
+	}
+	err = json.Unmarshal(totalsJSON, &totals)
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// accrueInterest adds the interest earned by account since it was last touched,
+// at the current rates, and marks it as touched at the transaction time.
+// It is applied on every read of an account, so any write of the account keeps it.
+func (s *UserContract) accrueInterest(ctx contractapi.TransactionContextInterface, account *UserAccount) error {
+	rates, err := s.ReadRateSchedule(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rates.Tiers) == 0 {
+		return nil
+	}
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return err
+	}
+	from := account.AccruedAt
+	if from == 0 {
+		from = rates.Since
+	}
+	if ts.Seconds <= from {
+		return nil
+	}
+	account.AccruedInterest = account.AccruedInterest + accrual(rates.Tiers, account.Balance, ts.Seconds-from)
+	account.AccruedAt = ts.Seconds
+	return nil
+}
+
+// accrual returns the interest in micro-units earned by balance over seconds.
+func accrual(tiers []*RateTier, balance int, seconds int64) int64 {
+	var total int64
+	for i, tier := range tiers {
+		if balance <= tier.Threshold {
+			break
+		}
+		portion := balance - tier.Threshold
+		if i+1 < len(tiers) && balance > tiers[i+1].Threshold {
+			portion = tiers[i+1].Threshold - tier.Threshold
+		}
+		// rate 는 연 베이시스 포인트: portion * rate / 10000 * seconds / 1년
+		total = total + int64(portion)*int64(tier.Rate)*seconds*(microUnits/10000)/secondsPerYear
+	}
+	return total
+}
@@ -0,0 +1,74 @@
+package chaincode_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostAccountInterest(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 100, "User2": 800, "User3": 995})
+	// 연 10%, 잔액 500 초과분은 연 20% 보관 수수료
+	l.peer("mychaincode", "centralbank-channel", answers(map[string]string{
+		"ReadRateSchedule": fmt.Sprintf(`{"tiers":[{"threshold":0,"rate":1000},{"threshold":500,"rate":-2000}],"since":%d}`, l.now.Unix()),
+	}))
+	require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error { return s.SetRateSchedule(ctx) }))
+	l.now = l.now.Add(365 * 24 * time.Hour)
+
+	post := func(c client, accountIDs ...string) (int, int, int, error) {
+		var accounts, paid, charged int
+		err := l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			posting, err := s.PostAccountInterest(ctx, accountIDs)
+			if err != nil {
+				return err
+			}
+			accounts, paid, charged = posting.Accounts, posting.Paid, posting.Charged
+			return nil
+		})
+		return accounts, paid, charged, err
+	}
+	_, _, _, err := post(operator("Bank1"), "User1")
+	requireCode(t, err, cbdcerr.Unauthorized, "only the central bank can post interest")
+
+	// User1: 100 * 10% = 10, User2: 500 * 10% - 300 * 20% = -10, User3: 500 * 10% - 495 * 20% = -49
+	accounts, paid, charged, err := post(regulator, "User1", "User2", "User3", "User1")
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 10, 59}, []int{accounts, paid, charged})
+	require.Equal(t, 110, l.balance("User1"))
+	require.Equal(t, 790, l.balance("User2"))
+	require.Equal(t, 946, l.balance("User3"))
+	for _, id := range []string{"User1", "User2", "User3"} {
+		require.Equal(t, int64(0), l.account(id).AccruedInterest)
+		require.Equal(t, l.now.Unix(), l.account(id).AccruedAt)
+	}
+
+	// 같은 시각에 다시 게시하면 새로 쌓인 이자가 없음
+	accounts, paid, charged, err = post(regulator, "User1")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 0, 0}, []int{accounts, paid, charged})
+
+	// 잔액 한도를 넘는 이자는 다음 게시까지 남음
+	l.put("User4", chaincode.UserAccount{ID: "User4", Balance: 995, AccruedInterest: 10 * 1000000, AccruedAt: l.now.Unix()})
+	accounts, paid, charged, err = post(regulator, "User4")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 5, 0}, []int{accounts, paid, charged})
+	require.Equal(t, 1000, l.balance("User4"))
+	require.Equal(t, int64(5*1000000), l.account("User4").AccruedInterest)
+
+	var totals struct{ accounts, paid, charged int }
+	require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		posted, err := s.ReadInterestTotals(ctx)
+		if err != nil {
+			return err
+		}
+		totals.accounts, totals.paid, totals.charged = posted.Accounts, posted.Paid, posted.Charged
+		return nil
+	}))
+	require.Equal(t, struct{ accounts, paid, charged int }{5, 15, 59}, totals)
+}
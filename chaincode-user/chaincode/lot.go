@@ -0,0 +1,275 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+// BalanceLot is a part of an account balance issued by the central bank for a stimulus
+// programme. Unless spent it expires at Expiry (unix seconds), and until then loses
+// Demurrage annual basis points of its amount, taken off by each sweep since DemurredAt.
+// TransferBalanceUser spends lots before the rest of the free balance, oldest first.
+type BalanceLot struct {
+	Issuer     string `json:"issuer"`
+	Amount     int    `json:"amount"`
+	Issued     int64  `json:"issued"`
+	Expiry     int64  `json:"expiry"`
+	Demurrage  int    `json:"demurrage,omitempty"`
+	DemurredAt int64  `json:"demurredAt,omitempty"`
+}
+
+const (
+	lotIssueKind   = "lotIssue"
+	sweepTotalsKey = "sweepTotals"
+)
+
+// lotSweep is the outcome of one sweep, or of all of them for the totals the central bank
+// chaincode reconciles the total issued with. Expired is what expired lots, and lots
+// retired by IssueBalanceLot, returned to the issuer and Demurrage what live lots lost.
+type lotSweep struct {
+	Accounts  int    `json:"accounts"`
+	Expired   int    `json:"expired"`
+	Demurrage int    `json:"demurrage"`
+	Date      string `json:"date"`
+}
+
+// lotIssue is an expiring balance issued by the central bank chaincode.
+type lotIssue struct {
+	ID        string `json:"ID"`
+	UserID    string `json:"userID"`
+	Amount    int    `json:"amount"`
+	Expiry    int64  `json:"expiry"`
+	Demurrage int    `json:"demurrage"`
+	Date      string `json:"date"`
+}
+
+// lotRetired is the payload of the LotRetired event.
+type lotRetired struct {
+	IssueID string `json:"issueID"`
+	UserID  string `json:"userID"`
+	Amount  int    `json:"amount"`
+	Reason  string `json:"reason"`
+}
+
+// spendLots takes spent out of the account's lots, oldest first, and then whatever else
+// the lots hold beyond the free balance, which debits that did not go through spendLots
+// have used. It is applied after the balance has been debited.
+func spendLots(account *UserAccount, spent int) {
+	lotTotal := 0
+	for _, lot := range account.Lots {
+		lotTotal = lotTotal + lot.Amount
+	}
+	if excess := lotTotal - freeBalance(account); excess > spent {
+		spent = excess
+	}
+	var lots []*BalanceLot
+	for _, lot := range account.Lots {
+		if spent > 0 {
+			use := lot.Amount
+			if use > spent {
+				use = spent
+			}
+			lot.Amount = lot.Amount - use
+			spent = spent - use
+		}
+		if lot.Amount > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	account.Lots = lots
+}
+
+// 중앙은행에서 만료형 CBDC 발행
+// IssueBalanceLot credits the user the expiring balance the central bank issued on its
+// channel with IssueExpiringBalance under issueID. A lot the account cannot take, over
+// MAX_VAL, blocked by screening or expired before it is applied, is retired: it is
+// counted as expired in the sweep totals, so that the central bank chaincode takes it
+// off the total issued, and a LotRetired event is emitted.
+func (s *UserContract) IssueBalanceLot(ctx contractapi.TransactionContextInterface, issueID string) error {
+	if !isRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can issue expiring balances")
+	}
+	payload, err := queryCentralBank(ctx, "ReadLotIssue", issueID)
+	if err != nil {
+		return err
+	}
+	var issue lotIssue
+	err = json.Unmarshal(payload, &issue)
+	if err != nil {
+		return err
+	}
+	err = s.markApplied(ctx, lotIssueKind, issueID)
+	if err != nil {
+		return err
+	}
+	account, err := s.ReadAccount(ctx, issue.UserID)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	newBal := account.Balance + issue.Amount
+	var reason error
+	switch {
+	case issue.Expiry <= now.Unix():
+		reason = cbdcerr.New(cbdcerr.Rejected, "the lot expired before it was applied")
+	case newBal > MAX_VAL:
+		reason = cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	default:
+		reason = s.screenAccounts(ctx, account)
+		if reason != nil && cbdcerr.CodeOf(reason) != cbdcerr.Blocked {
+			return reason
+		}
+	}
+	if reason != nil {
+		return s.retireLot(ctx, &issue, reason, now.Format("2006-01-02 15:04"))
+	}
+
+	spendLots(account, 0)
+	account.Balance = newBal
+	account.Lots = append(account.Lots, &BalanceLot{
+		Issuer:     "Central Bank",
+		Amount:     issue.Amount,
+		Issued:     now.Unix(),
+		Expiry:     issue.Expiry,
+		Demurrage:  issue.Demurrage,
+		DemurredAt: now.Unix(),
+	})
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	// 기록
+	s.TransferHistory(ctx, issue.UserID, "Central Bank", strconv.Itoa(issue.Amount))
+	return ctx.GetStub().PutState(issue.UserID, accountJSON)
+}
+
+// retireLot counts a lot that could not be credited as expired in the sweep totals.
+func (s *UserContract) retireLot(ctx contractapi.TransactionContextInterface, issue *lotIssue, reason error, date string) error {
+	err := s.addSweepTotals(ctx, &lotSweep{Expired: issue.Amount, Date: date})
+	if err != nil {
+		return err
+	}
+	eventJSON, err := json.Marshal(lotRetired{IssueID: issue.ID, UserID: issue.UserID, Amount: issue.Amount, Reason: reason.Error()})
+	if err != nil {
+		return err
+	}
+	return emitEvent(ctx, "LotRetired", eventJSON)
+}
+
+// SweepAccountLots returns the expired lots of the accounts to their issuer and takes
+// demurrage off the live ones, and records the sweep in the sweep totals, which the
+// central bank chaincode retires from the total issued with ReconcileLotSweeps.
+func (s *UserContract) SweepAccountLots(ctx contractapi.TransactionContextInterface, accountIDs []string) (*lotSweep, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the central bank can sweep expiring balances")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := newAccountCache()
+	sweep := lotSweep{Date: now.Format("2006-01-02 15:04")}
+	var history []*AccountHistory
+	for _, id := range accountIDs {
+		if _, ok := accounts.accounts[id]; ok {
+			continue
+		}
+		account, err := accounts.get(ctx, s, id)
+		if err != nil {
+			return nil, err
+		}
+		sweep.Accounts = sweep.Accounts + 1
+		spendLots(account, 0)
+
+		swept := make(map[string]int)
+		var issuers []string
+		var lots []*BalanceLot
+		for _, lot := range account.Lots {
+			taken := 0
+			if lot.Expiry <= now.Unix() {
+				taken = lot.Amount
+				sweep.Expired = sweep.Expired + taken
+			} else if lot.Demurrage > 0 {
+				taken = int(int64(lot.Amount) * int64(lot.Demurrage) * (now.Unix() - lot.DemurredAt) / (10000 * secondsPerYear))
+				// 1 단위 미만의 감가는 다음 정산까지 누적
+				if taken > 0 {
+					lot.DemurredAt = now.Unix()
+					sweep.Demurrage = sweep.Demurrage + taken
+				}
+			}
+			lot.Amount = lot.Amount - taken
+			if lot.Amount > 0 {
+				lots = append(lots, lot)
+			}
+			if taken == 0 {
+				continue
+			}
+			if _, ok := swept[lot.Issuer]; !ok {
+				issuers = append(issuers, lot.Issuer)
+			}
+			swept[lot.Issuer] = swept[lot.Issuer] + taken
+			account.Balance = account.Balance - taken
+		}
+		account.Lots = lots
+		for _, issuer := range issuers {
+			history = append(history, &AccountHistory{Receiver: issuer, Price: strconv.Itoa(swept[issuer]), Sender: id})
+		}
+	}
+
+	err = accounts.flush(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	err = s.transferHistories(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	err = s.addSweepTotals(ctx, &sweep)
+	if err != nil {
+		return nil, err
+	}
+	return &sweep, nil
+}
+
+// ReadSweepTotals returns what all sweeps, and the lots retired by IssueBalanceLot, took off.
+func (s *UserContract) ReadSweepTotals(ctx contractapi.TransactionContextInterface) (*lotSweep, error) {
+	totalsJSON, err := ctx.GetStub().GetState(sweepTotalsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	var totals lotSweep
+	if totalsJSON == nil {
+		return &totals, nil
+	}
+	err = json.Unmarshal(totalsJSON, &totals)
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+func (s *UserContract) addSweepTotals(ctx contractapi.TransactionContextInterface, sweep *lotSweep) error {
+	totals, err := s.ReadSweepTotals(ctx)
+	if err != nil {
+		return err
+	}
+	totals.Accounts = totals.Accounts + sweep.Accounts
+	totals.Expired = totals.Expired + sweep.Expired
+	totals.Demurrage = totals.Demurrage + sweep.Demurrage
+	totals.Date = sweep.Date
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(sweepTotalsKey, totalsJSON)
+}
@@ -0,0 +1,111 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// lotIssues is the central bank chaincode answering ReadLotIssue with the issues by ID.
+func lotIssues(issues map[string]string) peerChaincode {
+	return func(args []string) peer.Response {
+		issue, ok := issues[args[1]]
+		if args[0] != "ReadLotIssue" || !ok {
+			return shim.Error("unexpected call to " + args[0])
+		}
+		return shim.Success([]byte(issue))
+	}
+}
+
+func TestLotSweep(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 50, "User2": 0})
+	day := int64(24 * 60 * 60)
+	issue := func(userID string, amount int, expiry int64, demurrage int) string {
+		return fmt.Sprintf(`{"userID":"%s","amount":%d,"expiry":%d,"demurrage":%d}`, userID, amount, expiry, demurrage)
+	}
+	l.peer("mychaincode", "centralbank-channel", lotIssues(map[string]string{
+		"L1": issue("User1", 100, l.now.Unix()+10*day, 0),
+		"L2": issue("User2", 200, l.now.Unix()+730*day, 1000),
+		"L3": issue("User1", 900, l.now.Unix()+10*day, 0),
+		"L4": issue("User2", 10, l.now.Unix(), 0),
+	}))
+	apply := func(c client, issueID string) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error { return s.IssueBalanceLot(ctx, issueID) })
+	}
+	totals := func() (int, int, int) {
+		var accounts, expired, demurrage int
+		require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+			swept, err := s.ReadSweepTotals(ctx)
+			if err != nil {
+				return err
+			}
+			accounts, expired, demurrage = swept.Accounts, swept.Expired, swept.Demurrage
+			return nil
+		}))
+		return accounts, expired, demurrage
+	}
+
+	requireCode(t, apply(operator("Bank1"), "L1"), cbdcerr.Unauthorized, "only the central bank can issue expiring balances")
+	require.NoError(t, apply(regulator, "L1"))
+	require.NoError(t, apply(regulator, "L2"))
+	requireCode(t, apply(regulator, "L1"), cbdcerr.AlreadyExists, "the lotIssue L1 has already been applied")
+	require.Equal(t, 150, l.balance("User1"))
+	require.Equal(t, 200, l.balance("User2"))
+
+	// 받을 수 없는 발행분은 만료분으로 집계되어 중앙은행 발행 총액에서 빠짐
+	require.NoError(t, apply(regulator, "L3"))
+	require.Equal(t, "LotRetired", l.lastEvent().EventName)
+	var retired struct{ Reason string }
+	require.NoError(t, json.Unmarshal(l.lastEvent().Payload, &retired))
+	requireCode(t, cbdcerr.Parse(retired.Reason), cbdcerr.LimitExceeded, "Individuals cannot own more than 1000 in CBDC.")
+	require.NoError(t, apply(regulator, "L4"))
+	require.Equal(t, "LotRetired", l.lastEvent().EventName)
+	require.Equal(t, 150, l.balance("User1"))
+	require.Equal(t, 200, l.balance("User2"))
+	accounts, expired, demurrage := totals()
+	require.Equal(t, []int{0, 910, 0}, []int{accounts, expired, demurrage})
+
+	// 1년 뒤: L1 은 만료되어 회수, L2 는 연 10% 감가
+	l.now = l.now.Add(365 * 24 * time.Hour)
+	sweep := func(c client, accountIDs ...string) (int, int, int, error) {
+		var accounts, expired, demurrage int
+		err := l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			swept, err := s.SweepAccountLots(ctx, accountIDs)
+			if err != nil {
+				return err
+			}
+			accounts, expired, demurrage = swept.Accounts, swept.Expired, swept.Demurrage
+			return nil
+		})
+		return accounts, expired, demurrage, err
+	}
+	_, _, _, err := sweep(operator("Bank1"), "User1")
+	requireCode(t, err, cbdcerr.Unauthorized, "only the central bank can sweep expiring balances")
+	accounts, expired, demurrage, err = sweep(regulator, "User1", "User2", "User1")
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 100, 20}, []int{accounts, expired, demurrage})
+	require.Equal(t, 50, l.balance("User1"))
+	require.Empty(t, l.account("User1").Lots)
+	account := l.account("User2")
+	require.Equal(t, 180, account.Balance)
+	require.Equal(t, 180, account.Lots[0].Amount)
+	require.Equal(t, l.now.Unix(), account.Lots[0].DemurredAt)
+
+	// 같은 시각에 다시 정산하면 가져갈 것이 없음
+	accounts, expired, demurrage, err = sweep(regulator, "User2")
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 0, 0}, []int{accounts, expired, demurrage})
+	require.Equal(t, 180, l.balance("User2"))
+
+	accounts, expired, demurrage = totals()
+	require.Equal(t, []int{3, 1010, 20}, []int{accounts, expired, demurrage})
+}
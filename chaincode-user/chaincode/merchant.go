@@ -0,0 +1,359 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	merchantType       = "merchant"
+	paymentRequestType = "paymentRequest"
+	receiptType        = "receipt"
+
+	requestOpen      = "open"
+	requestPaid      = "paid"
+	requestCancelled = "cancelled"
+)
+
+// Merchant is a user account registered to accept payments.
+// Category is the merchant category code checked by tagged balances, set by the
+// settlement bank or the regulator.
+type Merchant struct {
+	ID             string `json:"ID"`
+	Name           string `json:"name"`
+	Category       string `json:"category"`
+	SettlementBank string `json:"settlementBank"`
+	PublicKey      string `json:"publicKey,omitempty"`
+}
+
+// PaymentRequest is an invoice a merchant issues for a payer to settle. Expiry is unix seconds.
+type PaymentRequest struct {
+	ID         string `json:"ID"`
+	MerchantID string `json:"merchantID"`
+	Price      int    `json:"price"`
+	Reference  string `json:"reference"`
+	Expiry     int64  `json:"expiry"`
+	Status     string `json:"status"`
+	ReceiptID  string `json:"receiptID,omitempty"`
+}
+
+// Receipt is the proof of a settled payment request, readable by both sides.
+type Receipt struct {
+	ID         string `json:"ID"`
+	RequestID  string `json:"requestID"`
+	MerchantID string `json:"merchantID"`
+	PayerID    string `json:"payerID"`
+	Price      int    `json:"price"`
+	Fee        int    `json:"fee,omitempty"`
+	Reference  string `json:"reference"`
+	Date       string `json:"date"`
+}
+
+// RegisterMerchant turns an existing user account into a merchant settling with bankID.
+// The merchant has no category until its settlement bank or the regulator sets one
+// with SetAccountCategory, so it cannot accept tagged balances on its own say.
+func (s *UserContract) RegisterMerchant(ctx contractapi.TransactionContextInterface, id string, bankID string) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner can register %s as a merchant", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	found, err := s.getRecord(ctx, merchantType, id, &Merchant{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the merchant %s already exists", id)
+	}
+
+	params := []string{"AccountExist", bankID}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+	if string(response.Payload) == "false" {
+		return cbdcerr.New(cbdcerr.NotFound, "the bank %s does not exist", bankID)
+	}
+
+	merchant := Merchant{
+		ID:             id,
+		Name:           account.Name,
+		SettlementBank: bankID,
+	}
+	return s.putRecord(ctx, merchantType, id, merchant)
+}
+
+func (s *UserContract) ReadMerchant(ctx contractapi.TransactionContextInterface, id string) (*Merchant, error) {
+	var merchant Merchant
+	found, err := s.getRecord(ctx, merchantType, id, &merchant)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the merchant %s does not exist", id)
+	}
+	return &merchant, nil
+}
+
+// CreatePaymentRequest issues a payment request from a merchant.
+func (s *UserContract) CreatePaymentRequest(ctx contractapi.TransactionContextInterface, requestID string, merchantID string, price int, reference string, expiry int64) error {
+	if !isAccountOwner(ctx, merchantID) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner can issue payment requests for %s", merchantID)
+	}
+	if _, err := s.ReadMerchant(ctx, merchantID); err != nil {
+		return err
+	}
+	if price <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	found, err := s.getRecord(ctx, paymentRequestType, requestID, &PaymentRequest{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the payment request %s already exists", requestID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiry <= now.Unix() {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "expiry must be in the future")
+	}
+
+	request := PaymentRequest{
+		ID:         requestID,
+		MerchantID: merchantID,
+		Price:      price,
+		Reference:  reference,
+		Expiry:     expiry,
+		Status:     requestOpen,
+	}
+	return s.putRecord(ctx, paymentRequestType, requestID, request)
+}
+
+func (s *UserContract) ReadPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string) (*PaymentRequest, error) {
+	var request PaymentRequest
+	found, err := s.getRecord(ctx, paymentRequestType, requestID, &request)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the payment request %s does not exist", requestID)
+	}
+	return &request, nil
+}
+
+// CancelPaymentRequest withdraws an open payment request.
+func (s *UserContract) CancelPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string) error {
+	request, err := s.ReadPaymentRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if !isAccountOwner(ctx, request.MerchantID) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner of %s can cancel its payment requests", request.MerchantID)
+	}
+	if request.Status != requestOpen {
+		return cbdcerr.New(cbdcerr.Rejected, "the payment request %s is already %s", requestID, request.Status)
+	}
+	request.Status = requestCancelled
+	return s.putRecord(ctx, paymentRequestType, requestID, request)
+}
+
+// PayPaymentRequest settles a payment request from the payer's account and returns the receipt.
+func (s *UserContract) PayPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string, payerID string) (*Receipt, error) {
+	if !isAccountOwner(ctx, payerID) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the owner can pay from %s", payerID)
+	}
+	request, err := s.ReadPaymentRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != requestOpen {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the payment request %s is already %s", requestID, request.Status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now.Unix() >= request.Expiry {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the payment request %s has expired", requestID)
+	}
+
+	receipt, err := s.settlePayment(ctx, payerID, request.MerchantID, request.Price, request.Reference, requestID)
+	if err != nil {
+		return nil, err
+	}
+	request.Status = requestPaid
+	request.ReceiptID = receipt.ID
+	err = s.putRecord(ctx, paymentRequestType, requestID, request)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// settlePayment moves price from the payer to the merchant, charges the payer the
+// merchant payment fee and stores the receipt.
+func (s *UserContract) settlePayment(ctx contractapi.TransactionContextInterface, payerID string, merchantID string, price int, reference string, requestID string) (*Receipt, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payer, err := s.ReadAccount(ctx, payerID)
+	if err != nil {
+		return nil, err
+	}
+	merchant, err := s.ReadAccount(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if payerID == merchantID {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "a merchant cannot pay itself")
+	}
+
+	free := freeBalance(payer)
+	if !spendTagged(payer, merchant.Category, price, now.Unix()) {
+		return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", payerID)
+	}
+	mBal := merchant.Balance + price
+	if mBal > MAX_VAL {
+		return nil, cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, payer, merchant)
+	if err != nil {
+		return nil, err
+	}
+	err = s.screenTransfer(ctx, payerID, merchantID, price)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := s.quoteFee(ctx, feeMerchant, payerID, price)
+	if err != nil {
+		return nil, err
+	}
+	payer.Balance = payer.Balance - price - quote.Fee
+	// 수수료도 자유 잔액에서 지불
+	if freeBalance(payer) < 0 {
+		return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", payerID)
+	}
+	err = s.payFee(ctx, quote)
+	if err != nil {
+		return nil, err
+	}
+	// 만료형 잔액은 먼저 발행된 것부터 사용
+	spendLots(payer, free-freeBalance(payer))
+	merchant.Balance = mBal
+
+	receipt := Receipt{
+		ID:         ctx.GetStub().GetTxID(),
+		RequestID:  requestID,
+		MerchantID: merchantID,
+		PayerID:    payerID,
+		Price:      price,
+		Fee:        quote.Fee,
+		Reference:  reference,
+		Date:       now.Format("2006-01-02 15:04"),
+	}
+	err = s.putRecord(ctx, receiptType, receipt.ID, receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	payerJSON, err := json.Marshal(payer)
+	if err != nil {
+		return nil, err
+	}
+	merchantJSON, err := json.Marshal(merchant)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(payerID, payerJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(merchantID, merchantJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	//기록
+	s.transferHistoryFee(ctx, merchantID, payerID, strconv.Itoa(price), quote.Fee)
+	return &receipt, nil
+}
+
+func (s *UserContract) ReadReceipt(ctx contractapi.TransactionContextInterface, receiptID string) (*Receipt, error) {
+	var receipt Receipt
+	found, err := s.getRecord(ctx, receiptType, receiptID, &receipt)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the receipt %s does not exist", receiptID)
+	}
+	return &receipt, nil
+}
+
+// ReadReceiptsUser returns the receipts where the user is the payer or the merchant.
+func (s *UserContract) ReadReceiptsUser(ctx contractapi.TransactionContextInterface, userID string) ([]*Receipt, error) {
+	receiptJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(receiptType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer receiptJSON.Close()
+	var receipts []*Receipt
+	for receiptJSON.HasNext() {
+		queryResponse, err := receiptJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var receipt Receipt
+		err = json.Unmarshal(queryResponse.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+		if receipt.PayerID == userID || receipt.MerchantID == userID {
+			receipts = append(receipts, &receipt)
+		}
+	}
+	return receipts, nil
+}
+
+// getRecord reads the record of objectType stored under id into v.
+func (s *UserContract) getRecord(ctx contractapi.TransactionContextInterface, objectType string, id string, v interface{}) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{id})
+	if err != nil {
+		return false, err
+	}
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if recordJSON == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(recordJSON, v)
+}
+
+func (s *UserContract) putRecord(ctx contractapi.TransactionContextInterface, objectType string, id string, v interface{}) error {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{id})
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, recordJSON)
+}
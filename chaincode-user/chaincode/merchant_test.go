@@ -0,0 +1,109 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// merchantFee is the regulatory chaincode charging fee on merchant payments, payable to Bank1.
+func merchantFee(fee string) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] == "QuoteFee" && args[1] == "merchant" {
+			return shim.Success([]byte(`{"txType":"merchant","fee":` + fee + `,"payee":"Bank1"}`))
+		}
+		return screen(args)
+	}
+}
+
+func TestRegisterMerchant(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"Shop1": 0})
+	register := func(c client) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			return s.RegisterMerchant(ctx, "Shop1", "Bank1")
+		})
+	}
+	setCategory := func(c client) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			return s.SetAccountCategory(ctx, "Shop1", "food")
+		})
+	}
+
+	requireCode(t, register(owner("User1")), cbdcerr.Unauthorized, "only the owner can register Shop1 as a merchant")
+	require.NoError(t, register(owner("Shop1")))
+	requireCode(t, register(owner("Shop1")), cbdcerr.AlreadyExists, "the merchant Shop1 already exists")
+
+	// 업종은 가맹점이 아닌 정산 은행이 지정
+	requireCode(t, setCategory(owner("Shop1")), cbdcerr.Unauthorized, "only the regulator or the settlement bank can set the category of Shop1")
+	requireCode(t, setCategory(operator("Bank2")), cbdcerr.Unauthorized, "only the regulator or the settlement bank can set the category of Shop1")
+	require.Equal(t, "", l.account("Shop1").Category)
+
+	require.NoError(t, setCategory(operator("Bank1")))
+	require.Equal(t, "food", l.account("Shop1").Category)
+	var merchant chaincode.Merchant
+	l.record("merchant", "Shop1", &merchant)
+	require.Equal(t, chaincode.Merchant{ID: "Shop1", Category: "food", SettlementBank: "Bank1"}, merchant)
+}
+
+func TestPayPaymentRequestFee(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"Shop1": 0})
+	account := chaincode.UserAccount{ID: "User1", Balance: 100}
+	account.Lots = []*chaincode.BalanceLot{{Issuer: "Central Bank", Amount: 100, Expiry: l.now.Unix() + 86400}}
+	l.put("User1", account)
+	require.NoError(t, l.tx(owner("Shop1"), func(ctx contractapi.TransactionContextInterface) error {
+		return s.RegisterMerchant(ctx, "Shop1", "Bank1")
+	}))
+	// 이전 방식으로 은행별 단일 키에 쌓인 미정산액도 합계에 포함
+	legacy, err := l.stub.CreateCompositeKey("bankReceivable", []string{"Bank1"})
+	require.NoError(t, err)
+	l.put(legacy, chaincode.BankReceivable{BankID: "Bank1", Total: 5})
+	l.peer("regulatorychaincode", "regulatory-channel", merchantFee("1"))
+
+	pay := func(requestID string, price int) (*chaincode.Receipt, error) {
+		require.NoError(t, l.tx(owner("Shop1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.CreatePaymentRequest(ctx, requestID, "Shop1", price, "order "+requestID, l.now.Unix()+3600)
+		}))
+		var receipt *chaincode.Receipt
+		err := l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			receipt, err = s.PayPaymentRequest(ctx, requestID, "User1")
+			return err
+		})
+		return receipt, err
+	}
+
+	// 수수료는 대금과 함께 자유 잔액에서 빠지고 만료형 잔액부터 사용
+	receipt, err := pay("R1", 30)
+	require.NoError(t, err)
+	require.Equal(t, 1, receipt.Fee)
+	account = *l.account("User1")
+	require.Equal(t, 69, account.Balance)
+	require.Equal(t, 69, account.Lots[0].Amount)
+	require.Equal(t, 30, l.balance("Shop1"))
+	require.Equal(t, 6, receivable(l, "Bank1"))
+
+	_, err = pay("R2", 69)
+	requireCode(t, err, cbdcerr.InsufficientFunds, "Lack of balance User1's Account")
+	require.Equal(t, 69, l.balance("User1"))
+
+	// 거래마다 따로 기록하고 정산 대상은 그 합계
+	second, err := pay("R3", 10)
+	require.NoError(t, err)
+	require.Equal(t, 58, l.balance("User1"))
+	require.Equal(t, 7, receivable(l, "Bank1"))
+	for _, txID := range []string{receipt.ID, second.ID} {
+		var entry chaincode.BankReceivable
+		key, err := l.stub.CreateCompositeKey("bankReceivable", []string{"Bank1", txID})
+		require.NoError(t, err)
+		l.get(key, &entry)
+		require.Equal(t, chaincode.BankReceivable{BankID: "Bank1", TxID: txID, Total: 1}, entry)
+	}
+}
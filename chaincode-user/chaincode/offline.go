@@ -0,0 +1,312 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/voucher"
+)
+
+const (
+	offlinePurseType   = "offlinePurse"
+	offlineVoucherType = "offlineVoucher"
+
+	purseOpen    = "open"
+	purseBlocked = "blocked"
+
+	voucherIssued      = "issued"
+	voucherRedeemed    = "redeemed"
+	voucherDoubleSpent = "doubleSpent"
+)
+
+// OfflinePurse is the part of a user balance loaded onto a device for payments without
+// connectivity. PublicKey is the key of the device's secure element, which endorses
+// the purse's vouchers. Until a voucher is redeemed its amount stays in the owner's
+// balance as Offline, which cannot be spent online.
+// A purse whose voucher is redeemed twice is blocked and cannot be loaded again.
+type OfflinePurse struct {
+	ID          string `json:"ID"`
+	Owner       string `json:"owner"`
+	PublicKey   string `json:"publicKey"`
+	Loaded      int    `json:"loaded"`
+	Redeemed    int    `json:"redeemed"`
+	Outstanding int    `json:"outstanding"`
+	NextSerial  int    `json:"nextSerial"`
+	Status      string `json:"status"`
+	DoubleSpent []int  `json:"doubleSpent,omitempty"`
+}
+
+// offlineVoucher is the ledger's record of an issued voucher.
+type offlineVoucher struct {
+	PurseID    string `json:"purseID"`
+	Serial     int    `json:"serial"`
+	Amount     int    `json:"amount"`
+	Status     string `json:"status"`
+	RedeemedBy string `json:"redeemedBy,omitempty"`
+}
+
+// VoucherRedemption is the outcome of redeeming a voucher.
+type VoucherRedemption struct {
+	PurseID    string       `json:"purseID"`
+	Serial     int          `json:"serial"`
+	Amount     int          `json:"amount"`
+	RedeemedBy string       `json:"redeemedBy"`
+	Status     string       `json:"status"`
+	Code       cbdcerr.Code `json:"code,omitempty"`
+}
+
+// LoadOfflinePurse moves the sum of denominations from the free balance of id into the
+// purse, creating it for the device key publicKey, and issues a voucher for each
+// denomination. The vouchers are returned to be stored on the device.
+func (s *UserContract) LoadOfflinePurse(ctx contractapi.TransactionContextInterface, purseID string, id string, publicKey string, denominations []int) ([]*voucher.Voucher, error) {
+	if !isAccountOwner(ctx, id) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the owner can load an offline purse from %s", id)
+	}
+	if _, err := voucher.ParsePublicKey(publicKey); err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid device key: %v", err)
+	}
+	if len(denominations) == 0 {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "at least one denomination is required")
+	}
+	total := 0
+	for _, amount := range denominations {
+		if amount <= 0 {
+			return nil, cbdcerr.New(cbdcerr.InvalidArgument, "denominations must be positive")
+		}
+		total = total + amount
+	}
+
+	purse := OfflinePurse{ID: purseID, Owner: id, PublicKey: publicKey, NextSerial: 1, Status: purseOpen}
+	found, err := s.getRecord(ctx, offlinePurseType, purseID, &purse)
+	if err != nil {
+		return nil, err
+	}
+	if found && (purse.Owner != id || purse.PublicKey != publicKey) {
+		return nil, cbdcerr.New(cbdcerr.AlreadyExists, "the purse %s belongs to another account or device", purseID)
+	}
+	if purse.Status == purseBlocked {
+		return nil, cbdcerr.New(cbdcerr.Blocked, "the purse %s is blocked after a double spend", purseID)
+	}
+
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if freeBalance(account) < total {
+		return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+	}
+	err = s.screenAccounts(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	account.Offline = account.Offline + total
+
+	var vouchers []*voucher.Voucher
+	for _, amount := range denominations {
+		v := voucher.Voucher{PurseID: purseID, Serial: purse.NextSerial, Amount: amount}
+		err = s.putOfflineVoucher(ctx, &offlineVoucher{PurseID: purseID, Serial: v.Serial, Amount: amount, Status: voucherIssued})
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, &v)
+		purse.NextSerial = purse.NextSerial + 1
+	}
+	purse.Loaded = purse.Loaded + total
+	purse.Outstanding = purse.Loaded - purse.Redeemed
+
+	err = s.putRecord(ctx, offlinePurseType, purseID, purse)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putAccount(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	return vouchers, nil
+}
+
+// RedeemVoucher pays a voucher, given as JSON, to the account id it was last endorsed to,
+// out of the offline balance of the purse owner. The owner redeems its own unspent
+// vouchers the same way to bring them back online.
+// A voucher already redeemed by another holder is a double spend: nothing is paid, the
+// purse is blocked and a DoubleSpend event is emitted, and the redemption reports the
+// conflict with the DoubleSpend code. The holder that redeemed it presenting it again
+// gets AlreadyExists.
+func (s *UserContract) RedeemVoucher(ctx contractapi.TransactionContextInterface, id string, voucherJSON string) (*VoucherRedemption, error) {
+	if !isAccountOwner(ctx, id) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the owner can redeem vouchers into %s", id)
+	}
+	var v voucher.Voucher
+	err := json.Unmarshal([]byte(voucherJSON), &v)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid voucher: %v", err)
+	}
+	var purse OfflinePurse
+	found, err := s.getRecord(ctx, offlinePurseType, v.PurseID, &purse)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the purse %s does not exist", v.PurseID)
+	}
+	issued, err := s.readOfflineVoucher(ctx, v.PurseID, v.Serial)
+	if err != nil {
+		return nil, err
+	}
+	if issued == nil || issued.Amount != v.Amount {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "the purse %s did not issue voucher %d for %d", v.PurseID, v.Serial, v.Amount)
+	}
+	holder, err := v.Verify(purse.PublicKey)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid voucher %s/%d: %v", v.PurseID, v.Serial, err)
+	}
+	if holder != id {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "the voucher %s/%d is not endorsed to %s", v.PurseID, v.Serial, id)
+	}
+
+	redemption := VoucherRedemption{PurseID: v.PurseID, Serial: v.Serial, Amount: v.Amount, RedeemedBy: id}
+	if issued.Status != voucherIssued {
+		// 같은 소지자의 재제출은 이중 사용이 아님
+		if issued.RedeemedBy == id {
+			return nil, cbdcerr.New(cbdcerr.AlreadyExists, "the voucher %s/%d has already been redeemed into %s", v.PurseID, v.Serial, id)
+		}
+		return s.recordDoubleSpend(ctx, &purse, issued, &redemption)
+	}
+
+	owner, err := s.ReadAccount(ctx, purse.Owner)
+	if err != nil {
+		return nil, err
+	}
+	owner.Offline = owner.Offline - v.Amount
+	if id == purse.Owner {
+		err = s.putAccount(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		receiver, err := s.ReadAccount(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		rBal := receiver.Balance + v.Amount
+		if rBal > MAX_VAL {
+			return nil, cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+		}
+		err = s.screenAccounts(ctx, owner, receiver)
+		if err != nil {
+			return nil, err
+		}
+		err = s.screenTransfer(ctx, purse.Owner, id, v.Amount)
+		if err != nil {
+			return nil, err
+		}
+		owner.Balance = owner.Balance - v.Amount
+		receiver.Balance = rBal
+
+		err = s.putAccount(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		err = s.putAccount(ctx, receiver)
+		if err != nil {
+			return nil, err
+		}
+		//기록
+		s.TransferHistory(ctx, id, purse.Owner, strconv.Itoa(v.Amount))
+	}
+
+	// 상환 시 지갑 잔액 정산
+	purse.Redeemed = purse.Redeemed + v.Amount
+	purse.Outstanding = purse.Loaded - purse.Redeemed
+	issued.Status = voucherRedeemed
+	issued.RedeemedBy = id
+	err = s.putOfflineVoucher(ctx, issued)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putRecord(ctx, offlinePurseType, purse.ID, purse)
+	if err != nil {
+		return nil, err
+	}
+	redemption.Status = voucherRedeemed
+	return &redemption, nil
+}
+
+// recordDoubleSpend blocks the purse of a voucher presented again and emits the conflict.
+func (s *UserContract) recordDoubleSpend(ctx contractapi.TransactionContextInterface, purse *OfflinePurse, issued *offlineVoucher, redemption *VoucherRedemption) (*VoucherRedemption, error) {
+	purse.Status = purseBlocked
+	purse.DoubleSpent = append(purse.DoubleSpent, issued.Serial)
+	issued.Status = voucherDoubleSpent
+
+	err := s.putOfflineVoucher(ctx, issued)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putRecord(ctx, offlinePurseType, purse.ID, purse)
+	if err != nil {
+		return nil, err
+	}
+	redemption.Status = voucherDoubleSpent
+	redemption.Code = cbdcerr.DoubleSpend
+	conflict := struct {
+		Purse     *OfflinePurse      `json:"purse"`
+		Voucher   *offlineVoucher    `json:"voucher"`
+		Presented *VoucherRedemption `json:"presented"`
+	}{purse, issued, redemption}
+	conflictJSON, err := json.Marshal(conflict)
+	if err != nil {
+		return nil, err
+	}
+	err = emitEvent(ctx, "DoubleSpend", conflictJSON)
+	if err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+func (s *UserContract) ReadOfflinePurse(ctx contractapi.TransactionContextInterface, purseID string) (*OfflinePurse, error) {
+	var purse OfflinePurse
+	found, err := s.getRecord(ctx, offlinePurseType, purseID, &purse)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the purse %s does not exist", purseID)
+	}
+	return &purse, nil
+}
+
+func (s *UserContract) readOfflineVoucher(ctx contractapi.TransactionContextInterface, purseID string, serial int) (*offlineVoucher, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(offlineVoucherType, []string{purseID, strconv.Itoa(serial)})
+	if err != nil {
+		return nil, err
+	}
+	voucherJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if voucherJSON == nil {
+		return nil, nil
+	}
+	var v offlineVoucher
+	err = json.Unmarshal(voucherJSON, &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (s *UserContract) putOfflineVoucher(ctx contractapi.TransactionContextInterface, v *offlineVoucher) error {
+	key, err := ctx.GetStub().CreateCompositeKey(offlineVoucherType, []string{v.PurseID, strconv.Itoa(v.Serial)})
+	if err != nil {
+		return err
+	}
+	voucherJSON, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, voucherJSON)
+}
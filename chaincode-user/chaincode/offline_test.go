@@ -0,0 +1,102 @@
+package chaincode_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/voucher"
+	"github.com/stretchr/testify/require"
+)
+
+// redeem redeems v into the account id as its owner.
+func redeem(l *ledger, id string, v *voucher.Voucher) (*chaincode.VoucherRedemption, error) {
+	s := chaincode.UserContract{}
+	voucherJSON, err := json.Marshal(v)
+	require.NoError(l.t, err)
+	var redemption *chaincode.VoucherRedemption
+	err = l.tx(owner(id), func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		redemption, err = s.RedeemVoucher(ctx, id, string(voucherJSON))
+		return err
+	})
+	return redemption, err
+}
+
+func newDeviceKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := voucher.GenerateKey()
+	require.NoError(t, err)
+	encoded, err := voucher.EncodePublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, encoded
+}
+
+// endorsed returns a copy of v endorsed to to.
+func endorsed(t *testing.T, v *voucher.Voucher, key *ecdsa.PrivateKey, to string) *voucher.Voucher {
+	c := *v
+	c.Endorsements = append([]*voucher.Endorsement(nil), v.Endorsements...)
+	require.NoError(t, c.Endorse(key, to))
+	return &c
+}
+
+func TestOfflineDoubleSpend(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 500, "User2": 100, "User3": 100})
+	purseKey, purseEncoded := newDeviceKey(t)
+	deviceKey, deviceEncoded := newDeviceKey(t)
+
+	var vouchers []*voucher.Voucher
+	require.NoError(t, l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		vouchers, err = s.LoadOfflinePurse(ctx, "purse1", "User1", purseEncoded, []int{50, 20})
+		return err
+	}))
+	require.Len(t, vouchers, 2)
+	require.Equal(t, 70, l.account("User1").Offline)
+
+	// 지갑에서 다른 기기로, 그 기기에서 User2 에게 오프라인 지급
+	held := endorsed(t, vouchers[0], purseKey, deviceEncoded)
+	paid := endorsed(t, held, deviceKey, "User2")
+
+	_, err := redeem(l, "User3", paid)
+	requireCode(t, err, cbdcerr.Unauthorized, "the voucher purse1/1 is not endorsed to User3")
+
+	redemption, err := redeem(l, "User2", paid)
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.VoucherRedemption{PurseID: "purse1", Serial: 1, Amount: 50, RedeemedBy: "User2", Status: "redeemed"}, redemption)
+	require.Equal(t, 450, l.balance("User1"))
+	require.Equal(t, 20, l.account("User1").Offline)
+	require.Equal(t, 150, l.balance("User2"))
+
+	// User2 resubmitting its voucher is not a double spend
+	_, err = redeem(l, "User2", paid)
+	requireCode(t, err, cbdcerr.AlreadyExists, "the voucher purse1/1 has already been redeemed into User2")
+	require.Equal(t, 150, l.balance("User2"))
+	require.Nil(t, l.lastEvent())
+
+	// the device spends its copy of the voucher again
+	redemption, err = redeem(l, "User3", endorsed(t, held, deviceKey, "User3"))
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.VoucherRedemption{PurseID: "purse1", Serial: 1, Amount: 50, RedeemedBy: "User3", Status: "doubleSpent", Code: cbdcerr.DoubleSpend}, redemption)
+	require.Equal(t, 450, l.balance("User1"))
+	require.Equal(t, 100, l.balance("User3"))
+
+	var purse *chaincode.OfflinePurse
+	require.NoError(t, l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+		purse, err = s.ReadOfflinePurse(ctx, "purse1")
+		return err
+	}))
+	require.Equal(t, "blocked", purse.Status)
+	require.Equal(t, []int{1}, purse.DoubleSpent)
+	require.Equal(t, 50, purse.Redeemed)
+	require.Equal(t, 20, purse.Outstanding)
+
+	err = l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.LoadOfflinePurse(ctx, "purse1", "User1", purseEncoded, []int{10})
+		return err
+	})
+	requireCode(t, err, cbdcerr.Blocked, "the purse purse1 is blocked after a double spend")
+}
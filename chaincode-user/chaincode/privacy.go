@@ -0,0 +1,269 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	// accountPrivateCollection held the personal data of all account holders before
+	// each bank had its own collection. Accounts without a PrivateCollection are still
+	// read from it until MigrateAccountPrivateData moves them.
+	accountPrivateCollection = "accountPrivateCollection"
+
+	// bankCollectionPrefix prefixes the MSP ID of a bank to name the collection holding
+	// the personal data of its customers. Only the bank and the central bank are members,
+	// see collections_config.json.
+	bankCollectionPrefix = "accountPrivate_"
+
+	// accountTransientKey is the transient map entry carrying AccountPrivateDetails.
+	accountTransientKey = "account"
+
+	// accountIndexType indexes the accounts by ID, apart from the other keys of the
+	// world state, for TotalSupply.
+	accountIndexType = "accountIndex"
+)
+
+// AccountPrivateDetails is the personal data of an account holder. It is kept in the
+// private data collection; the public account only stores the SHA-256 of it.
+// Salt is chosen by the bank so the public hash cannot be guessed from a name.
+type AccountPrivateDetails struct {
+	ID        string `json:"ID"`
+	Name      string `json:"name"`
+	BirthDate string `json:"birthDate"`
+	Address   string `json:"address"`
+	IDNumber  string `json:"idNumber"`
+	KYCLevel  string `json:"kycLevel"`
+	Salt      string `json:"salt"`
+}
+
+// ReadAccountPrivateDetails returns the personal data of an account. Only a bank or the regulator can read it.
+func (s *UserContract) ReadAccountPrivateDetails(ctx contractapi.TransactionContextInterface, id string) (*AccountPrivateDetails, error) {
+	if !isBankOrRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only a bank or the regulator can read the details of %s", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	details, err := s.readAccountPrivateDetails(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the account %s has no private details", id)
+	}
+	return details, nil
+}
+
+// UpdateAccountPrivateDetails replaces the personal data of an account with the
+// AccountPrivateDetails passed in the transient map under "account".
+func (s *UserContract) UpdateAccountPrivateDetails(ctx contractapi.TransactionContextInterface, id string) error {
+	if !isBankOrRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only a bank or the regulator can update the details of %s", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !canWriteCollection(ctx, account) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "the details of %s are kept by another bank", id)
+	}
+	details, err := transientAccountDetails(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.screenAccounts(ctx, &UserAccount{ID: id, Name: details.Name})
+	if err != nil {
+		return err
+	}
+	return s.putAccountPrivateDetails(ctx, account, details)
+}
+
+// MigrateAccountPrivateData moves the personal data of an older account into the
+// collection of the calling bank: a name created before the private data collections
+// from the public world state, or details from the shared accountPrivateCollection.
+// Accounts kept in a bank collection before the name hash was public get it added.
+func (s *UserContract) MigrateAccountPrivateData(ctx contractapi.TransactionContextInterface, id string) error {
+	if !isBankOrRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only a bank or the regulator can migrate %s", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if account.Name != "" {
+		details := AccountPrivateDetails{ID: id, Name: account.Name}
+		account.Name = ""
+		return s.putAccountPrivateDetails(ctx, account, &details)
+	}
+	if account.PersonalHash == "" || (account.PrivateCollection != "" && account.NameHash != "") {
+		return cbdcerr.New(cbdcerr.Rejected, "the account %s has no personal data to migrate", id)
+	}
+	details, err := s.readAccountPrivateDetails(ctx, account)
+	if err != nil {
+		return err
+	}
+	if details == nil {
+		return cbdcerr.New(cbdcerr.NotFound, "the account %s has no private details", id)
+	}
+	return s.putAccountPrivateDetails(ctx, account, details)
+}
+
+// VerifyAccountPrivateDetails reports whether the private details stored for an account
+// match its public hash. Peers outside the collection can call it as well.
+func (s *UserContract) VerifyAccountPrivateDetails(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	hash, err := ctx.GetStub().GetPrivateDataHash(accountCollection(account), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private data hash: %v", err)
+	}
+	return hash != nil && hex.EncodeToString(hash) == account.PersonalHash, nil
+}
+
+// transientAccountDetails reads the personal data of account id from the transient map.
+func transientAccountDetails(ctx contractapi.TransactionContextInterface, id string) (*AccountPrivateDetails, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient map: %v", err)
+	}
+	detailsJSON, ok := transient[accountTransientKey]
+	if !ok {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "the %s entry must be passed in the transient map", accountTransientKey)
+	}
+	var details AccountPrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "invalid account details: %v", err)
+	}
+	if details.Name == "" {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "the account details must include a name")
+	}
+	details.ID = id
+	return &details, nil
+}
+
+// accountCollection returns the collection holding the personal data of account.
+func accountCollection(account *UserAccount) string {
+	if account.PrivateCollection == "" {
+		return accountPrivateCollection
+	}
+	return account.PrivateCollection
+}
+
+// clientCollection returns the collection of the calling bank. The central bank has
+// no collection of its own; accounts it opens are kept with the commercial bank.
+func clientCollection(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	switch mspID {
+	case commercialbankMSP:
+		return bankCollectionPrefix + mspID, nil
+	case centralbankMSP:
+		return bankCollectionPrefix + commercialbankMSP, nil
+	}
+	return "", cbdcerr.New(cbdcerr.Unauthorized, "%s keeps no account details", mspID)
+}
+
+// canWriteCollection reports whether the client may replace the details of account:
+// the central bank always, a bank only in its own collection.
+func canWriteCollection(ctx contractapi.TransactionContextInterface, account *UserAccount) bool {
+	if isRegulator(ctx) || account.PrivateCollection == "" {
+		return true
+	}
+	collection, err := clientCollection(ctx)
+	return err == nil && collection == account.PrivateCollection
+}
+
+// putAccountPrivateDetails stores details in the collection of the account and their
+// hash on the public account, together with the watch-list hash of the name so that
+// peers outside the collection can screen the holder, and indexes the account. Accounts without a collection of
+// their own are moved to the calling bank's, out of the shared accountPrivateCollection.
+func (s *UserContract) putAccountPrivateDetails(ctx contractapi.TransactionContextInterface, account *UserAccount, details *AccountPrivateDetails) error {
+	if account.PrivateCollection == "" {
+		collection, err := clientCollection(ctx)
+		if err != nil {
+			return err
+		}
+		if account.PersonalHash != "" {
+			err = ctx.GetStub().DelPrivateData(accountPrivateCollection, account.ID)
+			if err != nil {
+				return fmt.Errorf("failed to delete private data: %v", err)
+			}
+		}
+		account.PrivateCollection = collection
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutPrivateData(account.PrivateCollection, account.ID, detailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put private data: %v", err)
+	}
+
+	digest := sha256.Sum256(detailsJSON)
+	account.PersonalHash = hex.EncodeToString(digest[:])
+	account.NameHash = watchListHash(details.Name)
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(accountIndexType, []string{account.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(indexKey, []byte{0x00})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(account.ID, accountJSON)
+}
+
+func (s *UserContract) readAccountPrivateDetails(ctx contractapi.TransactionContextInterface, account *UserAccount) (*AccountPrivateDetails, error) {
+	detailsJSON, err := ctx.GetStub().GetPrivateData(accountCollection(account), account.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private data: %v", err)
+	}
+	if detailsJSON == nil {
+		return nil, nil
+	}
+	var details AccountPrivateDetails
+	err = json.Unmarshal(detailsJSON, &details)
+	if err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// accountNameHash returns the watch-list hash of the holder's name, or "" if the account
+// has no name. Accounts moved to a collection keep the hash on the public account, so
+// they are screened the same whoever calls. Older ones without it are screened from
+// their private details; when the endorsing peer cannot read them the name cannot be
+// screened and the operation fails rather than screening the ID alone.
+func (s *UserContract) accountNameHash(ctx contractapi.TransactionContextInterface, account *UserAccount) (string, error) {
+	if account.Name != "" {
+		return watchListHash(account.Name), nil
+	}
+	if account.NameHash != "" || account.PersonalHash == "" {
+		return account.NameHash, nil
+	}
+	details, err := s.readAccountPrivateDetails(ctx, account)
+	if err != nil {
+		return "", cbdcerr.New(cbdcerr.Blocked, "the name of %s cannot be screened: %v", account.ID, err)
+	}
+	if details == nil {
+		return "", cbdcerr.New(cbdcerr.Blocked, "the name of %s cannot be screened: no private details on this peer", account.ID)
+	}
+	return watchListHash(details.Name), nil
+}
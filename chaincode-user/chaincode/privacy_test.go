@@ -0,0 +1,48 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScreenPrivateAccount(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 300})
+	open := func(id string, name string) {
+		l.transient = map[string][]byte{"account": []byte(`{"name":"` + name + `","salt":"s1"}`)}
+		require.NoError(t, l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.CreateAccount(ctx, id) }))
+		l.transient = nil
+	}
+	pay := func(rec string) error {
+		return l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.TransferBalanceUser(ctx, "Bank1", "User1", rec, 100)
+		})
+	}
+
+	open("User2", "Alice Park")
+	open("User3", "Mallory  Kim")
+	l.peer("regulatorychaincode", "regulatory-channel", watchList("mallory kim"))
+	account := l.account("User3")
+	require.Equal(t, "", account.Name)
+	require.Equal(t, "accountPrivate_commercialbankOrg", account.PrivateCollection)
+
+	// 고객 조직의 피어는 개인 정보를 읽을 수 없으므로 공개된 이름 해시로 심사
+	require.NoError(t, pay("User2"))
+	requireCode(t, pay("User3"), cbdcerr.Blocked, "operation blocked: a party is on the watch list (version 3): "+hashed("mallory kim"))
+	require.Equal(t, 200, l.balance("User1"))
+
+	// 이름 해시가 없는 계좌는 은행이 이전하기 전까지 심사할 수 없음
+	account = l.account("User2")
+	account.NameHash = ""
+	l.put("User2", account)
+	requireCode(t, pay("User2"), cbdcerr.Blocked, "the name of User2 cannot be screened: failed to read private data: tx creator does not have read access permission on privatedata in chaincodeName:userchaincode collectionName: accountPrivate_commercialbankOrg")
+	require.NoError(t, l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error {
+		return s.MigrateAccountPrivateData(ctx, "User2")
+	}))
+	require.Equal(t, hashed("alice park"), l.account("User2").NameHash)
+	require.NoError(t, pay("User2"))
+}
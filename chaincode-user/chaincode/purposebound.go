@@ -0,0 +1,230 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+// TaggedBalance is the part of an account balance that may only be spent for a purpose.
+// It can only be paid to accounts whose category is in Categories, until Expiry (unix seconds).
+type TaggedBalance struct {
+	Purpose    string   `json:"purpose"`
+	Issuer     string   `json:"issuer"`
+	Amount     int      `json:"amount"`
+	Categories []string `json:"categories"`
+	Expiry     int64    `json:"expiry"`
+}
+
+const taggedIssueKind = "taggedIssue"
+
+// taggedIssue is a purpose-bound payment the regulatory chaincode debited from a bank.
+type taggedIssue struct {
+	ID         string   `json:"ID"`
+	BankID     string   `json:"bankID"`
+	UserID     string   `json:"userID"`
+	Amount     int      `json:"amount"`
+	Purpose    string   `json:"purpose"`
+	Categories []string `json:"categories"`
+	Expiry     int64    `json:"expiry"`
+}
+
+// freeBalance is the balance that can be spent without restriction.
+// Amounts on hold for a dispute, locked in an HTLC or loaded into an offline purse
+// are not free either.
+func freeBalance(account *UserAccount) int {
+	free := account.Balance - account.Held - account.Locked - account.Offline
+	for _, tag := range account.Tagged {
+		free = free - tag.Amount
+	}
+	return free
+}
+
+func (t *TaggedBalance) allows(category string) bool {
+	for _, c := range t.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// spendTagged takes price out of the account's tagged balances allowed for category,
+// earliest expiry first, and leaves the rest to the free balance.
+// It returns false without changing the account when the two together are not enough.
+func spendTagged(account *UserAccount, category string, price int, now int64) bool {
+	var usable []*TaggedBalance
+	for _, tag := range account.Tagged {
+		if category != "" && tag.Expiry > now && tag.allows(category) {
+			usable = append(usable, tag)
+		}
+	}
+	sort.SliceStable(usable, func(i, j int) bool { return usable[i].Expiry < usable[j].Expiry })
+
+	fromTagged := 0
+	for _, tag := range usable {
+		fromTagged = fromTagged + tag.Amount
+	}
+	if fromTagged > price {
+		fromTagged = price
+	}
+	if price-fromTagged > freeBalance(account) {
+		return false
+	}
+
+	remain := fromTagged
+	for _, tag := range usable {
+		if remain == 0 {
+			break
+		}
+		use := tag.Amount
+		if use > remain {
+			use = remain
+		}
+		tag.Amount = tag.Amount - use
+		remain = remain - use
+	}
+
+	var tagged []*TaggedBalance
+	for _, tag := range account.Tagged {
+		if tag.Amount > 0 {
+			tagged = append(tagged, tag)
+		}
+	}
+	account.Tagged = tagged
+	return true
+}
+
+// SetAccountCategory sets the merchant category that tagged balances are checked against,
+// on the account and on its merchant record. Only the regulator or the settlement bank
+// of a registered merchant can set it.
+func (s *UserContract) SetAccountCategory(ctx contractapi.TransactionContextInterface, id string, category string) error {
+	var merchant Merchant
+	found, err := s.getRecord(ctx, merchantType, id, &merchant)
+	if err != nil {
+		return err
+	}
+	if !isRegulator(ctx) && !(found && isBankOperator(ctx, merchant.SettlementBank)) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the regulator or the settlement bank can set the category of %s", id)
+	}
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if found {
+		merchant.Category = category
+		err = s.putRecord(ctx, merchantType, id, merchant)
+		if err != nil {
+			return err
+		}
+	}
+	account.Category = category
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, accountJSON)
+}
+
+// 은행에서 용도 지정 CBDC 발행
+// IssueTaggedBalance credits a purpose-bound balance the regulatory chaincode committed
+// with UpdateSendTaggedBalance, after debiting the bank. Each issue is credited once.
+func (s *UserContract) IssueTaggedBalance(ctx contractapi.TransactionContextInterface, issueID string) error {
+	payload, err := queryRegulatory(ctx, "ReadTaggedIssue", issueID)
+	if err != nil {
+		return err
+	}
+	var issue taggedIssue
+	err = json.Unmarshal(payload, &issue)
+	if err != nil {
+		return err
+	}
+	err = s.markApplied(ctx, taggedIssueKind, issueID)
+	if err != nil {
+		return err
+	}
+	account, err := s.ReadAccount(ctx, issue.UserID)
+	if err != nil {
+		return err
+	}
+
+	newBal := account.Balance + issue.Amount
+	if newBal > MAX_VAL {
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, account)
+	if err != nil {
+		return err
+	}
+	account.Balance = newBal
+	account.Tagged = append(account.Tagged, &TaggedBalance{
+		Purpose:    issue.Purpose,
+		Issuer:     issue.BankID,
+		Amount:     issue.Amount,
+		Categories: issue.Categories,
+		Expiry:     issue.Expiry,
+	})
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	// 기록
+	s.TransferHistory(ctx, issue.UserID, issue.BankID, strconv.Itoa(issue.Amount))
+	return ctx.GetStub().PutState(issue.UserID, accountJSON)
+}
+
+// ClawbackExpiredBalance returns the user's expired tagged balances to their issuers.
+// The issuers are owed the amounts, which the regulatory chaincode credits to them
+// with SettleBankReceivable.
+func (s *UserContract) ClawbackExpiredBalance(ctx contractapi.TransactionContextInterface, id string) error {
+	account, err := s.ReadAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	expired := make(map[string]int)
+	var issuers []string
+	var tagged []*TaggedBalance
+	for _, tag := range account.Tagged {
+		if tag.Expiry > now.Unix() {
+			tagged = append(tagged, tag)
+			continue
+		}
+		if _, ok := expired[tag.Issuer]; !ok {
+			issuers = append(issuers, tag.Issuer)
+		}
+		expired[tag.Issuer] = expired[tag.Issuer] + tag.Amount
+	}
+	if len(issuers) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, issuer := range issuers {
+		err = s.oweBank(ctx, issuer, expired[issuer])
+		if err != nil {
+			return err
+		}
+		total = total + expired[issuer]
+	}
+
+	account.Balance = account.Balance - total
+	account.Tagged = tagged
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+
+	// 기록
+	s.TransferHistory(ctx, strings.Join(issuers, ","), id, strconv.Itoa(total))
+	return ctx.GetStub().PutState(id, accountJSON)
+}
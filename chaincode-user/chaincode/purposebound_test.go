@@ -0,0 +1,87 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// taggedIssues is the regulatory chaincode with the tagged issues committed on its channel.
+func taggedIssues(issues map[string]interface{}) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] != "ReadTaggedIssue" {
+			return screen(args)
+		}
+		issue, ok := issues[args[1]]
+		if !ok {
+			return shim.Error(cbdcerr.New(cbdcerr.NotFound, "the tagged issue %s does not exist", args[1]).Error())
+		}
+		issueJSON, err := json.Marshal(issue)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(issueJSON)
+	}
+}
+
+func TestTaggedBalance(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 100, "User2": 0})
+	l.put("Shop1", chaincode.UserAccount{ID: "Shop1", Category: "food"})
+	expiry := l.now.Unix() + 86400
+	l.peer("regulatorychaincode", "regulatory-channel", taggedIssues(map[string]interface{}{
+		"relief1": map[string]interface{}{"ID": "relief1", "bankID": "Bank1", "userID": "User1", "amount": 200, "purpose": "disaster", "categories": []string{"food"}, "expiry": expiry},
+		"relief2": map[string]interface{}{"ID": "relief2", "bankID": "Bank1", "userID": "User1", "amount": 100, "purpose": "disaster", "categories": []string{"food"}, "expiry": expiry},
+	}))
+	issue := func(issueID string) error {
+		return l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.IssueTaggedBalance(ctx, issueID) })
+	}
+	pay := func(rec string, price int) error {
+		return l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.TransferBalanceUser(ctx, "Bank1", "User1", rec, price)
+		})
+	}
+
+	require.NoError(t, issue("relief1"))
+	requireCode(t, issue("relief1"), cbdcerr.AlreadyExists, "the taggedIssue relief1 has already been applied")
+	require.Equal(t, 300, l.balance("User1"))
+
+	// 용도 지정 잔액은 다른 개인에게 보낼 수 없음
+	requireCode(t, pay("User2", 150), cbdcerr.InsufficientFunds, "Lack of balance User1's Account")
+	require.NoError(t, pay("Shop1", 250))
+	account := l.account("User1")
+	require.Equal(t, 50, account.Balance)
+	require.Empty(t, account.Tagged)
+
+	require.NoError(t, issue("relief2"))
+	require.NoError(t, pay("Shop1", 30))
+	require.Equal(t, 70, l.account("User1").Tagged[0].Amount)
+
+	clawback := func() error {
+		return l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error { return s.ClawbackExpiredBalance(ctx, "User1") })
+	}
+	require.NoError(t, clawback())
+	require.Equal(t, 120, l.balance("User1"))
+
+	l.now = l.now.Add(48 * time.Hour)
+	require.NoError(t, clawback())
+	account = l.account("User1")
+	require.Equal(t, 50, account.Balance)
+	require.Empty(t, account.Tagged)
+
+	var receivable *chaincode.BankReceivable
+	require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		receivable, err = s.ReadBankReceivable(ctx, "Bank1")
+		return err
+	}))
+	require.Equal(t, 70, receivable.Total)
+}
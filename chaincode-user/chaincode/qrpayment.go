@@ -0,0 +1,86 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/payload"
+)
+
+const qrNonceType = "qrNonce"
+
+// SetMerchantKey registers the PEM encoded public key a merchant signs QR payloads with.
+func (s *UserContract) SetMerchantKey(ctx contractapi.TransactionContextInterface, id string, publicKey string) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only the owner can set the payload key of %s", id)
+	}
+	merchant, err := s.ReadMerchant(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := payload.ParsePublicKey(publicKey); err != nil {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "invalid public key for the merchant %s: %v", id, err)
+	}
+	merchant.PublicKey = publicKey
+	return s.putRecord(ctx, merchantType, id, merchant)
+}
+
+// PayQRPayload pays a merchant from a signed QR payload.
+// Each nonce is accepted once per merchant so a scanned code cannot be replayed.
+func (s *UserContract) PayQRPayload(ctx contractapi.TransactionContextInterface, payerID string, qr string) (*Receipt, error) {
+	if !isAccountOwner(ctx, payerID) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the owner can pay from %s", payerID)
+	}
+	p, err := payload.Parse(qr)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "%v", err)
+	}
+	merchant, err := s.ReadMerchant(ctx, p.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+	if merchant.PublicKey == "" {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the merchant %s has no payload key", p.MerchantID)
+	}
+	pub, err := payload.ParsePublicKey(merchant.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	p, err = payload.Verify(qr, pub)
+	if err != nil {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "%v", err)
+	}
+	if p.Currency != CBDC_NAME {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "unsupported currency %s", p.Currency)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.Expired(now.Unix()) {
+		return nil, cbdcerr.New(cbdcerr.Rejected, "the payload has expired")
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(qrNonceType, []string{p.MerchantID, p.Nonce})
+	if err != nil {
+		return nil, err
+	}
+	used, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if used != nil {
+		return nil, cbdcerr.New(cbdcerr.AlreadyExists, "the payload nonce %s has already been used", p.Nonce)
+	}
+
+	receipt, err := s.settlePayment(ctx, payerID, p.MerchantID, p.Amount, p.Reference, "")
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(key, []byte(receipt.ID))
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
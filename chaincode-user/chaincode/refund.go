@@ -0,0 +1,230 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	refundType      = "refund"
+	refundTotalType = "refundTotal"
+	swappedType     = "historySwapped"
+)
+
+// Refund returns part or all of a transfer recorded in the history.
+// Override is set when a bank or the regulator issued it instead of the receiver.
+type Refund struct {
+	ID        string `json:"ID"`
+	HistoryID string `json:"historyID"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Price     int    `json:"price"`
+	Date      string `json:"date"`
+	Override  bool   `json:"override"`
+}
+
+func (s *UserContract) readHistory(ctx contractapi.TransactionContextInterface, historyID string) (*AccountHistory, error) {
+	historyJSON, err := ctx.GetStub().GetState(historyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	if historyJSON == nil {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the history %s does not exist", historyID)
+	}
+	var history AccountHistory
+	err = json.Unmarshal(historyJSON, &history)
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// SwapHistoryParties corrects history entries written by TransferBalanceUser before it
+// recorded the receiver and the sender in order, so they can be refunded. The regulator
+// passes the IDs of those entries, found from the transactions that wrote them.
+// Each entry is swapped once; entries already swapped are skipped.
+func (s *UserContract) SwapHistoryParties(ctx contractapi.TransactionContextInterface, historyIDs []string) ([]*AccountHistory, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can migrate the history")
+	}
+	var swapped []*AccountHistory
+	seen := make(map[string]bool)
+	for _, historyID := range historyIDs {
+		found, err := s.getRecord(ctx, swappedType, historyID, new(string))
+		if err != nil {
+			return nil, err
+		}
+		if found || seen[historyID] {
+			continue
+		}
+		seen[historyID] = true
+		history, err := s.readHistory(ctx, historyID)
+		if err != nil {
+			return nil, err
+		}
+		history.Receiver, history.Sender = history.Sender, history.Receiver
+		historyJSON, err := json.Marshal(history)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().PutState(historyID, historyJSON)
+		if err != nil {
+			return nil, err
+		}
+		err = s.putRecord(ctx, swappedType, historyID, ctx.GetStub().GetTxID())
+		if err != nil {
+			return nil, err
+		}
+		swapped = append(swapped, history)
+	}
+	return swapped, nil
+}
+
+// RefundedAmount returns how much of a history entry has been refunded so far.
+func (s *UserContract) RefundedAmount(ctx contractapi.TransactionContextInterface, historyID string) (int, error) {
+	refunded := 0
+	_, err := s.getRecord(ctx, refundTotalType, historyID, &refunded)
+	return refunded, err
+}
+
+// RefundTransferUser sends price back from the receiver of a history entry to its sender.
+// The receiver has to authorize it, or a bank or the regulator can override.
+// A bank sender is owed the refund, which the regulatory chaincode credits to it
+// with SettleBankReceivable.
+// Refunds of one entry together can not exceed its original price.
+func (s *UserContract) RefundTransferUser(ctx contractapi.TransactionContextInterface, historyID string, price int) (*Refund, error) {
+	history, err := s.readHistory(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	override := !isAccountOwner(ctx, history.Receiver)
+	if override && !isBankOrRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only %s or a bank can refund the history %s", history.Receiver, historyID)
+	}
+
+	original, e := strconv.Atoi(history.Price)
+	if e != nil {
+		return nil, e
+	}
+	refunded, err := s.RefundedAmount(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if price <= 0 {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "price must be positive")
+	}
+	if refunded+price > original {
+		return nil, cbdcerr.New(cbdcerr.LimitExceeded, "refund of %d exceeds the remaining %d of the history %s", price, original-refunded, historyID)
+	}
+
+	from, err := s.ReadAccount(ctx, history.Receiver)
+	if err != nil {
+		return nil, err
+	}
+	if freeBalance(from) < price {
+		return nil, cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", history.Receiver)
+	}
+	from.Balance = from.Balance - price
+
+	// 원거래 송신자가 은행이면 은행 계좌로 환불
+	to, err := s.ReadAccount(ctx, history.Sender)
+	if err != nil && cbdcerr.CodeOf(err) != cbdcerr.NotFound {
+		return nil, err
+	}
+	if err != nil {
+		payload, err := queryRegulatory(ctx, "AccountExist", history.Sender)
+		if err != nil {
+			return nil, err
+		}
+		if string(payload) != "true" {
+			return nil, cbdcerr.New(cbdcerr.NotFound, "the sender %s of the history %s does not exist", history.Sender, historyID)
+		}
+		err = s.oweBank(ctx, history.Sender, price)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		toBal := to.Balance + price
+		if toBal > MAX_VAL {
+			return nil, cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+		}
+		to.Balance = toBal
+		toJSON, err := json.Marshal(to)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().PutState(to.ID, toJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refund := Refund{
+		ID:        ctx.GetStub().GetTxID(),
+		HistoryID: historyID,
+		From:      history.Receiver,
+		To:        history.Sender,
+		Price:     price,
+		Date:      now.Format("2006-01-02 15:04"),
+		Override:  override,
+	}
+	refundJSON, err := json.Marshal(refund)
+	if err != nil {
+		return nil, err
+	}
+	refundKey, err := ctx.GetStub().CreateCompositeKey(refundType, []string{historyID, refund.ID})
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(refundKey, refundJSON)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putRecord(ctx, refundTotalType, historyID, refunded+price)
+	if err != nil {
+		return nil, err
+	}
+	fromJSON, err := json.Marshal(from)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(from.ID, fromJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	//기록
+	s.TransferHistory(ctx, history.Sender, history.Receiver, strconv.Itoa(price))
+	return &refund, nil
+}
+
+// ReadRefunds returns the refunds made against a history entry.
+func (s *UserContract) ReadRefunds(ctx contractapi.TransactionContextInterface, historyID string) ([]*Refund, error) {
+	refundJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(refundType, []string{historyID})
+	if err != nil {
+		return nil, err
+	}
+	defer refundJSON.Close()
+	var refunds []*Refund
+	for refundJSON.HasNext() {
+		queryResponse, err := refundJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var refund Refund
+		err = json.Unmarshal(queryResponse.Value, &refund)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, &refund)
+	}
+	return refunds, nil
+}
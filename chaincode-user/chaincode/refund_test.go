@@ -0,0 +1,63 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func refund(l *ledger, c client, historyID string, price int) error {
+	s := chaincode.UserContract{}
+	return l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RefundTransferUser(ctx, historyID, price)
+		return err
+	})
+}
+
+func receivable(l *ledger, bankID string) int {
+	s := chaincode.UserContract{}
+	var total int
+	require.NoError(l.t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+		receivable, err := s.ReadBankReceivable(ctx, bankID)
+		if err != nil {
+			return err
+		}
+		total = receivable.Total
+		return nil
+	}))
+	return total
+}
+
+func TestRefundToUser(t *testing.T) {
+	l := newLedger(t, map[string]int{"User1": 100, "User2": 300})
+	l.put("1", chaincode.AccountHistory{ID: "1", Receiver: "User2", Sender: "User1", Price: "200"})
+
+	requireCode(t, refund(l, owner("User1"), "1", 50), cbdcerr.Unauthorized, "only User2 or a bank can refund the history 1")
+	require.NoError(t, refund(l, owner("User2"), "1", 150))
+	require.Equal(t, 250, l.balance("User1"))
+	require.Equal(t, 150, l.balance("User2"))
+
+	requireCode(t, refund(l, owner("User2"), "1", 60), cbdcerr.LimitExceeded, "refund of 60 exceeds the remaining 50 of the history 1")
+	require.NoError(t, refund(l, operator("Bank1"), "1", 50))
+	require.Equal(t, 300, l.balance("User1"))
+	require.Equal(t, 100, l.balance("User2"))
+}
+
+func TestRefundToBank(t *testing.T) {
+	l := newLedger(t, map[string]int{"User1": 100})
+	l.put("1", chaincode.AccountHistory{ID: "1", Receiver: "User1", Sender: "Bank1", Price: "100"})
+	l.put("2", chaincode.AccountHistory{ID: "2", Receiver: "User1", Sender: "Bank9", Price: "100"})
+
+	require.NoError(t, refund(l, owner("User1"), "1", 40))
+	require.NoError(t, refund(l, owner("User1"), "1", 20))
+	require.Equal(t, 40, l.balance("User1"))
+	// 은행 계좌는 규제 채널에서 SettleBankReceivable 로 입금
+	require.Equal(t, 60, receivable(l, "Bank1"))
+
+	l.peer("regulatorychaincode", "regulatory-channel", answers(map[string]string{"AccountExist": "false"}))
+	requireCode(t, refund(l, owner("User1"), "2", 10), cbdcerr.NotFound, "the sender Bank9 of the history 2 does not exist")
+	require.Equal(t, 40, l.balance("User1"))
+}
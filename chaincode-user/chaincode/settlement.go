@@ -0,0 +1,135 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	bankReceivableType = "bankReceivable"
+	appliedType        = "applied"
+)
+
+// BankReceivable is the running total of CBDC that left user accounts towards a bank,
+// such as clawed back tagged balances and payment fees. InvokeChaincode on another
+// channel only reads, so the bank account is not credited from here: the regulatory
+// chaincode reads this total with SettleBankReceivable and credits what it has not
+// settled yet.
+//
+// Each transaction owing the bank stores its own entry, keyed by bank and tx ID, so
+// concurrent payments to the same bank do not conflict; the total is their sum.
+type BankReceivable struct {
+	BankID string `json:"bankID"`
+	TxID   string `json:"txID,omitempty"`
+	Total  int    `json:"total"`
+}
+
+// txReceivables holds what each running transaction owes each bank so far, by tx ID.
+// GetState does not see the transaction's own writes, so a second oweBank to the same
+// bank rewrites its entry from here.
+var txReceivables = struct {
+	sync.Mutex
+	m map[string]map[string]int
+}{m: make(map[string]map[string]int)}
+
+// appliedRecord marks a record committed on the regulatory channel, such as a tagged
+// balance issue, as applied to the user accounts so it is not applied twice.
+type appliedRecord struct {
+	Kind string `json:"kind"`
+	ID   string `json:"ID"`
+	TxID string `json:"txID"`
+}
+
+// queryRegulatory evaluates a function of the regulatory chaincode and returns its payload.
+// Anything the function writes is discarded.
+func queryRegulatory(ctx contractapi.TransactionContextInterface, params ...string) ([]byte, error) {
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return nil, cbdcerr.FromResponse(response)
+	}
+	return response.Payload, nil
+}
+
+// queryCentralBank evaluates a function of the central bank chaincode and returns its payload.
+// Anything the function writes is discarded.
+func queryCentralBank(ctx contractapi.TransactionContextInterface, params ...string) ([]byte, error) {
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("mychaincode", queryArgs, "centralbank-channel")
+	if response.Status != 200 {
+		return nil, cbdcerr.FromResponse(response)
+	}
+	return response.Payload, nil
+}
+
+// oweBank adds amount to what the user channel owes bankID, in the entry of the transaction.
+func (s *UserContract) oweBank(ctx contractapi.TransactionContextInterface, bankID string, amount int) error {
+	txID := ctx.GetStub().GetTxID()
+	txReceivables.Lock()
+	owed, ok := txReceivables.m[txID]
+	if !ok {
+		owed = make(map[string]int)
+		txReceivables.m[txID] = owed
+	}
+	owed[bankID] = owed[bankID] + amount
+	total := owed[bankID]
+	txReceivables.Unlock()
+
+	key, err := ctx.GetStub().CreateCompositeKey(bankReceivableType, []string{bankID, txID})
+	if err != nil {
+		return err
+	}
+	entryJSON, err := json.Marshal(BankReceivable{BankID: bankID, TxID: txID, Total: total})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, entryJSON)
+}
+
+// ReadBankReceivable returns the running total the user channel owes bankID: the sum of
+// the entries of every transaction, and of the single total kept before them.
+func (s *UserContract) ReadBankReceivable(ctx contractapi.TransactionContextInterface, bankID string) (*BankReceivable, error) {
+	entryJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(bankReceivableType, []string{bankID})
+	if err != nil {
+		return nil, err
+	}
+	defer entryJSON.Close()
+	receivable := BankReceivable{BankID: bankID}
+	for entryJSON.HasNext() {
+		queryResponse, err := entryJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var entry BankReceivable
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, err
+		}
+		receivable.Total = receivable.Total + entry.Total
+	}
+	return &receivable, nil
+}
+
+// markApplied records that the regulatory record kind/id has been applied.
+// It fails if it already was.
+func (s *UserContract) markApplied(ctx contractapi.TransactionContextInterface, kind string, id string) error {
+	found, err := s.getRecord(ctx, appliedType, kind+":"+id, &appliedRecord{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the %s %s has already been applied", kind, id)
+	}
+	return s.putRecord(ctx, appliedType, kind+":"+id, appliedRecord{Kind: kind, ID: id, TxID: ctx.GetStub().GetTxID()})
+}
@@ -0,0 +1,310 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	standingOrderType    = "standingOrder"
+	standingOrderRunType = "standingOrderRun"
+
+	orderActive    = "active"
+	orderCancelled = "cancelled"
+	orderFinished  = "finished"
+
+	runPaid    = "paid"
+	runSkipped = "skipped"
+)
+
+// StandingOrder pays Price from Sender to Receiver every Months months from NextRun until End.
+// NextRun and End are unix seconds.
+type StandingOrder struct {
+	ID       string `json:"ID"`
+	Sender   string `json:"sender"`
+	Receiver string `json:"receiver"`
+	Price    int    `json:"price"`
+	Months   int    `json:"months"`
+	NextRun  int64  `json:"nextRun"`
+	End      int64  `json:"end"`
+	Runs     int    `json:"runs"`
+	Status   string `json:"status"`
+}
+
+// StandingOrderRun is the outcome of one scheduled execution of a standing order.
+type StandingOrderRun struct {
+	OrderID string       `json:"orderID"`
+	Run     int          `json:"run"`
+	Owner   string       `json:"owner"`
+	Due     int64        `json:"due"`
+	Status  string       `json:"status"`
+	Code    cbdcerr.Code `json:"code,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+	Date    string       `json:"date"`
+}
+
+// RegisterStandingOrder pays price from id to rec every months months,
+// starting at start and ending at end (unix seconds).
+func (s *UserContract) RegisterStandingOrder(ctx contractapi.TransactionContextInterface, orderID string, id string, rec string, price int, months int, start int64, end int64) error {
+	if !isAccountOwner(ctx, id) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can register its standing orders", id)
+	}
+	if price <= 0 || months <= 0 {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "price and months must be positive")
+	}
+	found, err := s.getRecord(ctx, standingOrderType, orderID, &StandingOrder{})
+	if err != nil {
+		return err
+	}
+	if found {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the standing order %s already exists", orderID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if start < now.Unix() || end < start {
+		return cbdcerr.New(cbdcerr.InvalidArgument, "the standing order must start in the future and end after it starts")
+	}
+	if _, err := s.ReadAccount(ctx, rec); err != nil {
+		return err
+	}
+
+	order := StandingOrder{
+		ID:       orderID,
+		Sender:   id,
+		Receiver: rec,
+		Price:    price,
+		Months:   months,
+		NextRun:  start,
+		End:      end,
+		Status:   orderActive,
+	}
+	return s.putRecord(ctx, standingOrderType, orderID, order)
+}
+
+// CancelStandingOrder stops a standing order. Only its owner can cancel it.
+func (s *UserContract) CancelStandingOrder(ctx contractapi.TransactionContextInterface, orderID string) error {
+	order, err := s.ReadStandingOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if !isAccountOwner(ctx, order.Sender) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only %s can cancel the standing order %s", order.Sender, orderID)
+	}
+	if order.Status != orderActive {
+		return cbdcerr.New(cbdcerr.Rejected, "the standing order %s is already %s", orderID, order.Status)
+	}
+	order.Status = orderCancelled
+	return s.putRecord(ctx, standingOrderType, orderID, order)
+}
+
+// RunStandingOrders executes the given standing orders that are due, once each.
+// Only the scheduler can run them. A run that cannot be paid is recorded as skipped
+// and all skipped runs of the batch are reported in one StandingOrderSkipped event.
+// When the scheduler runs late, only the latest due run is paid and each earlier
+// missed one is recorded as skipped.
+func (s *UserContract) RunStandingOrders(ctx contractapi.TransactionContextInterface, orderIDs []string) ([]*StandingOrderRun, error) {
+	if !isScheduler(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the scheduler can run standing orders")
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := newAccountCache()
+	fees := make(map[string]int)
+	var payees []string
+	var runs []*StandingOrderRun
+	var skipped []*StandingOrderRun
+	var history []*AccountHistory
+	seen := make(map[string]bool)
+	for _, orderID := range orderIDs {
+		if seen[orderID] {
+			continue
+		}
+		seen[orderID] = true
+		order, err := s.ReadStandingOrder(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order.Status != orderActive || order.NextRun > now.Unix() {
+			continue
+		}
+
+		for order.Status == orderActive && order.NextRun <= now.Unix() {
+			run := StandingOrderRun{
+				OrderID: orderID,
+				Run:     order.Runs + 1,
+				Owner:   order.Sender,
+				Due:     order.NextRun,
+				Status:  runPaid,
+				Date:    now.Format("2006-01-02 15:04"),
+			}
+			next := time.Unix(order.NextRun, 0).UTC().AddDate(0, order.Months, 0).Unix()
+			if next <= now.Unix() && next <= order.End {
+				// 다음 회차도 이미 도래했으면 이번 회차는 놓친 것으로 기록
+				run.Status = runSkipped
+				run.Code = cbdcerr.Rejected
+				run.Reason = fmt.Sprintf("the run was missed: the next run was due on %s", time.Unix(next, 0).UTC().Format("2006-01-02 15:04"))
+				skipped = append(skipped, &run)
+			} else {
+				quote, reason, err := s.payStandingOrder(ctx, accounts, order, now.Unix())
+				if err != nil {
+					return nil, err
+				}
+				if reason != nil {
+					run.Status = runSkipped
+					run.Code = reason.Code
+					run.Reason = reason.Message
+					skipped = append(skipped, &run)
+				} else {
+					his := AccountHistory{Receiver: order.Receiver, Price: strconv.Itoa(order.Price), Sender: order.Sender}
+					if quote.Fee > 0 {
+						his.Fee = strconv.Itoa(quote.Fee)
+						if _, ok := fees[quote.Payee]; !ok {
+							payees = append(payees, quote.Payee)
+						}
+						fees[quote.Payee] = fees[quote.Payee] + quote.Fee
+					}
+					history = append(history, &his)
+				}
+			}
+
+			order.Runs = run.Run
+			order.NextRun = next
+			if order.NextRun > order.End {
+				order.Status = orderFinished
+			}
+			runKey, err := ctx.GetStub().CreateCompositeKey(standingOrderRunType, []string{orderID, fmt.Sprintf("%06d", run.Run)})
+			if err != nil {
+				return nil, err
+			}
+			runJSON, err := json.Marshal(run)
+			if err != nil {
+				return nil, err
+			}
+			err = ctx.GetStub().PutState(runKey, runJSON)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, &run)
+		}
+		err = s.putRecord(ctx, standingOrderType, orderID, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = accounts.flush(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	// 같은 수취 은행의 수수료는 한 번에 기록
+	for _, payee := range payees {
+		err = s.payFee(ctx, &feeQuote{Payee: payee, Fee: fees[payee]})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = s.transferHistories(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		skippedJSON, err := json.Marshal(skipped)
+		if err != nil {
+			return nil, err
+		}
+		err = emitEvent(ctx, "StandingOrderSkipped", skippedJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+// payStandingOrder pays one run of order and its fee, spending expiring lots first.
+// It returns the fee quote, which the caller records with payFee, and why the run was
+// skipped, or nil when it was paid.
+func (s *UserContract) payStandingOrder(ctx contractapi.TransactionContextInterface, accounts *accountCache, order *StandingOrder, now int64) (*feeQuote, *cbdcerr.Error, error) {
+	sender, err := accounts.get(ctx, s, order.Sender)
+	if err != nil {
+		return nil, cbdcerr.From(err), nil
+	}
+	receiver, err := accounts.get(ctx, s, order.Receiver)
+	if err != nil {
+		return nil, cbdcerr.From(err), nil
+	}
+	if receiver.Balance+order.Price > MAX_VAL {
+		return nil, &cbdcerr.Error{Code: cbdcerr.LimitExceeded, Message: fmt.Sprintf("Individuals cannot own more than %d in CBDC.", MAX_VAL)}, nil
+	}
+	err = s.screenAccounts(ctx, sender, receiver)
+	if err != nil {
+		return nil, cbdcerr.From(err), nil
+	}
+	err = s.screenTransfer(ctx, sender.ID, receiver.ID, order.Price)
+	if err != nil {
+		return nil, cbdcerr.From(err), nil
+	}
+	txType := feeP2P
+	if receiver.Category != "" {
+		txType = feeMerchant
+	}
+	quote, err := s.quoteFee(ctx, txType, sender.ID, order.Price)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 용도 지정 잔액은 허용된 업종에서만 사용하고, 수수료는 자유 잔액에서 지불
+	free := freeBalance(sender)
+	sender.Balance = sender.Balance - quote.Fee
+	if !spendTagged(sender, receiver.Category, order.Price, now) {
+		sender.Balance = sender.Balance + quote.Fee
+		return nil, &cbdcerr.Error{Code: cbdcerr.InsufficientFunds, Message: fmt.Sprintf("Lack of balance %s's Account", sender.ID)}, nil
+	}
+	sender.Balance = sender.Balance - order.Price
+	spendLots(sender, free-freeBalance(sender))
+	receiver.Balance = receiver.Balance + order.Price
+	return quote, nil, nil
+}
+
+func (s *UserContract) ReadStandingOrder(ctx contractapi.TransactionContextInterface, orderID string) (*StandingOrder, error) {
+	var order StandingOrder
+	found, err := s.getRecord(ctx, standingOrderType, orderID, &order)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the standing order %s does not exist", orderID)
+	}
+	return &order, nil
+}
+
+// ReadStandingOrderRuns returns the recorded runs of a standing order.
+func (s *UserContract) ReadStandingOrderRuns(ctx contractapi.TransactionContextInterface, orderID string) ([]*StandingOrderRun, error) {
+	runJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(standingOrderRunType, []string{orderID})
+	if err != nil {
+		return nil, err
+	}
+	defer runJSON.Close()
+	var runs []*StandingOrderRun
+	for runJSON.HasNext() {
+		queryResponse, err := runJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var run StandingOrderRun
+		err = json.Unmarshal(queryResponse.Value, &run)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,66 @@
+package chaincode_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandingOrders(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User2": 0, "User3": 0})
+	l.put("User1", chaincode.UserAccount{ID: "User1", Balance: 500, Lots: []*chaincode.BalanceLot{{Issuer: "Central Bank", Amount: 300, Expiry: l.now.AddDate(1, 0, 0).Unix()}}})
+	l.peer("regulatorychaincode", "regulatory-channel", p2pFee("2"))
+	start := l.now.Add(time.Hour)
+	register := func(orderID string, rec string, price int, start time.Time, end time.Time) error {
+		return l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.RegisterStandingOrder(ctx, orderID, "User1", rec, price, 1, start.Unix(), end.Unix())
+		})
+	}
+	cancel := func(c client) error {
+		return l.tx(c, func(ctx contractapi.TransactionContextInterface) error { return s.CancelStandingOrder(ctx, "order1") })
+	}
+
+	requireCode(t, register("order1", "User2", 100, l.now.Add(-time.Hour), start), cbdcerr.InvalidArgument, "the standing order must start in the future and end after it starts")
+	require.NoError(t, register("order1", "User2", 100, start, start.AddDate(0, 5, 0)))
+	require.NoError(t, register("order2", "User3", 50, start, time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC)))
+
+	// 두 달 늦게 실행하면 놓친 회차는 건너뛴 것으로 기록하고 최근 회차만 지급
+	l.now = time.Date(2026, 12, 1, 12, 0, 0, 0, time.UTC)
+	var runs []*chaincode.StandingOrderRun
+	require.NoError(t, l.tx(scheduler, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		runs, err = s.RunStandingOrders(ctx, []string{"order1", "order2"})
+		return err
+	}))
+	require.Equal(t, "StandingOrderSkipped", l.lastEvent().EventName)
+	var statuses []string
+	for _, run := range runs {
+		statuses = append(statuses, run.OrderID+"/"+run.Status)
+	}
+	require.Equal(t, []string{"order1/skipped", "order1/skipped", "order1/paid", "order2/skipped", "order2/paid"}, statuses)
+	require.Equal(t, cbdcerr.Rejected, runs[0].Code)
+	require.Equal(t, "the run was missed: the next run was due on 2026-11-01 10:00", runs[0].Reason)
+
+	account := l.account("User1")
+	require.Equal(t, 346, account.Balance)
+	require.Equal(t, 146, account.Lots[0].Amount)
+	require.Equal(t, 100, l.balance("User2"))
+	require.Equal(t, 50, l.balance("User3"))
+	require.Equal(t, 4, receivable(l, "Bank1"))
+
+	var order chaincode.StandingOrder
+	l.record("standingOrder", "order2", &order)
+	require.Equal(t, "finished", order.Status)
+	l.record("standingOrder", "order1", &order)
+	require.Equal(t, 3, order.Runs)
+	require.Equal(t, time.Date(2027, 1, 1, 10, 0, 0, 0, time.UTC).Unix(), order.NextRun)
+
+	requireCode(t, cancel(owner("User2")), cbdcerr.Unauthorized, "only User1 can cancel the standing order order1")
+	require.NoError(t, cancel(owner("User1")))
+	requireCode(t, cancel(owner("User1")), cbdcerr.Rejected, "the standing order order1 is already cancelled")
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 	"strconv"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
 )
 
 
@@ -17,6 +18,7 @@ type UserContract struct {
 
 const (
 	MAX_VAL int = 1000
+	CBDC_NAME string = "korea"
 )
 
 // Asset describes basic details of what makes up a simple asset
@@ -24,6 +26,19 @@ type UserAccount struct {
 	ID             string `json:"ID"`
 	Name 		   string `json:"name"`
 	Balance		   int 	  `json:"balance"`
+	Category	   string `json:"category,omitempty"`
+	Tagged		   []*TaggedBalance `json:"tagged,omitempty"`
+	Held		   int	  `json:"held,omitempty"`
+	Locked		   int	  `json:"locked,omitempty"`
+	Offline		   int	  `json:"offline,omitempty"`
+	PersonalHash   string `json:"personalHash,omitempty"`
+	NameHash	   string `json:"nameHash,omitempty"`
+	PrivateCollection string `json:"privateCollection,omitempty"`
+	Commitment	   string `json:"commitment,omitempty"`
+	ConfidentialKey string `json:"confidentialKey,omitempty"`
+	AccruedInterest int64  `json:"accruedInterest,omitempty"`
+	AccruedAt	   int64  `json:"accruedAt,omitempty"`
+	Lots		   []*BalanceLot `json:"lots,omitempty"`
 }
 
 type AccountHistory struct {
@@ -32,6 +47,7 @@ type AccountHistory struct {
 	Price			string `json:"price"`
 	Date			string `json:"date"`
 	Sender 			string `json:"sender"`
+	Fee				string `json:"fee,omitempty"`
 }
 
 
@@ -45,12 +61,10 @@ func (s *UserContract) InitLedger(ctx contractapi.TransactionContextInterface) e
 	}
 
 	for _, account := range accounts {
-		accountJSON, err := json.Marshal(account)
-		if err != nil {
-			return err
-		}
-
-		err = ctx.GetStub().PutState(account.ID, accountJSON)
+		// 이름은 공개 원장이 아닌 개인 데이터 컬렉션에 저장
+		details := AccountPrivateDetails{ID: account.ID, Name: account.Name}
+		account.Name = ""
+		err := s.putAccountPrivateDetails(ctx, &account, &details)
 		if err != nil {
 			return fmt.Errorf("failed to put to world state. %v", err)
 		}
@@ -59,6 +73,33 @@ func (s *UserContract) InitLedger(ctx contractapi.TransactionContextInterface) e
 	return nil
 }
 
+// CreateAccount opens a new user account after screening it against the watch list.
+// The holder's AccountPrivateDetails are passed in the transient map under "account"
+// and kept in the private data collection; only their hash is public.
+func (s *UserContract) CreateAccount(ctx contractapi.TransactionContextInterface, id string) error {
+	if !isBankOrRegulator(ctx) {
+		return cbdcerr.New(cbdcerr.Unauthorized, "only a bank or the regulator can open the account %s", id)
+	}
+	existing, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return fmt.Errorf("failed to read world state: %v", err)
+	}
+	if existing != nil {
+		return cbdcerr.New(cbdcerr.AlreadyExists, "the account %s already exists", id)
+	}
+	details, err := transientAccountDetails(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.screenAccounts(ctx, &UserAccount{ID: id, Name: details.Name})
+	if err != nil {
+		return err
+	}
+	account := UserAccount{ID: id, Balance: 0}
+	return s.putAccountPrivateDetails(ctx, &account, details)
+}
+
 func (s *UserContract) ReadAccount(ctx contractapi.TransactionContextInterface, id string) (*UserAccount, error) {
 	accountJSON, err := ctx.GetStub().GetState(id)
 
@@ -66,12 +107,17 @@ func (s *UserContract) ReadAccount(ctx contractapi.TransactionContextInterface,
 		return nil, fmt.Errorf("failed to read world state: %v", err)
 	}
 	if accountJSON == nil {
-		return nil, fmt.Errorf("the account %s does not exist", id)
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the account %s does not exist", id)
 	}
 
 	var account UserAccount
 	err = json.Unmarshal(accountJSON, &account)
 
+	if err != nil {
+		return nil, err
+	}
+	// 계좌를 읽을 때마다 마지막 접근 이후의 이자를 적립
+	err = s.accrueInterest(ctx, &account)
 	if err != nil {
 		return nil, err
 	}
@@ -89,10 +135,14 @@ func (s *UserContract) UpdateAccount(ctx contractapi.TransactionContextInterface
 	if e != nil {
 		return e
 	}
+	err = s.screenAccounts(ctx, account)
+	if err != nil {
+		return err
+	}
 
 	newBal := account.Balance + balNum
 	if newBal > MAX_VAL {
-		return fmt.Errorf("Individuals cannot own more than %s in CBDC.", MAX_VAL)
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
 	}
 	account.Balance = newBal
 	accountJSON, err := json.Marshal(account)
@@ -115,10 +165,14 @@ func (s *UserContract) UpdateUserAccount(ctx contractapi.TransactionContextInter
 	if e != nil {
 		return e
 	}
+	err = s.screenAccounts(ctx, account)
+	if err != nil {
+		return err
+	}
 
 	newBal := account.Balance + balNum
 	if newBal > MAX_VAL {
-		return fmt.Errorf("Individuals cannot own more than %s in CBDC.", MAX_VAL)
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
 	}
 	account.Balance = newBal
 	accountJSON, err := json.Marshal(account)
@@ -140,15 +194,52 @@ func (s *UserContract) TransferBalanceUser(ctx contractapi.TransactionContextInt
 		return err
 	}
 
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	// 용도 지정 잔액은 허용된 업종에서만 사용
+	free := freeBalance(sender)
+	if !spendTagged(sender, receiver.Category, price, now.Unix()) {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
+	}
+
 	sBal := sender.Balance - price
 	rBal := receiver.Balance + price
 	if sBal < 0 {
-		return fmt.Errorf("Lack of balance %s's Account", id)
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
 	}
 	if rBal > MAX_VAL {
-		return fmt.Errorf("Individuals cannot own more than %s in CBDC.", MAX_VAL)
+		return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+	}
+	err = s.screenAccounts(ctx, sender, receiver)
+	if err != nil {
+		return err
+	}
+	err = s.screenTransfer(ctx, id, rec, price)
+	if err != nil {
+		return err
+	}
+	// 가맹점 결제와 개인 간 송금은 수수료 일정이 다름
+	txType := feeP2P
+	if receiver.Category != "" {
+		txType = feeMerchant
+	}
+	quote, err := s.quoteFee(ctx, txType, id, price)
+	if err != nil {
+		return err
+	}
+	sender.Balance = sBal - quote.Fee
+	// 수수료도 자유 잔액에서 지불
+	if freeBalance(sender) < 0 {
+		return cbdcerr.New(cbdcerr.InsufficientFunds, "Lack of balance %s's Account", id)
 	}
-	sender.Balance = sBal
+	err = s.payFee(ctx, quote)
+	if err != nil {
+		return err
+	}
+	// 만료형 잔액은 먼저 발행된 것부터 사용
+	spendLots(sender, free-freeBalance(sender))
 	// receiver.Balance = rBal
 
 	senderJSON, sErr := json.Marshal(sender)
@@ -167,17 +258,23 @@ func (s *UserContract) TransferBalanceUser(ctx contractapi.TransactionContextInt
 	}
 	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
 	if response.Status != 200 {
-		return fmt.Errorf("Failed to query chaincode. Got Error: %s", response.Payload)
+		return cbdcerr.FromResponse(response)
 	}
 
 	// ctx.GetStub().PutState(rec, receiverJSON)
 
 	//기록 
-	s.TransferHistory(ctx, id, rec, strconv.Itoa(price))
+	// 이전 버전은 송신자와 수신자를 바꿔 기록함. 기존 항목은 SwapHistoryParties로 이전
+	s.transferHistoryFee(ctx, rec, id, strconv.Itoa(price), quote.Fee)
 	return ctx.GetStub().PutState(id, senderJSON)
 }
 
 func (s *UserContract) TransferHistory(ctx contractapi.TransactionContextInterface, rec string, sen string, price string) error {
+	return s.transferHistoryFee(ctx, rec, sen, price, 0)
+}
+
+// transferHistoryFee records a transfer together with the fee the sender paid on it.
+func (s *UserContract) transferHistoryFee(ctx contractapi.TransactionContextInterface, rec string, sen string, price string, fee int) error {
 	history, err := s.ReadTransferHistory(ctx)
 	if err != nil {
 		return err
@@ -192,6 +289,9 @@ func (s *UserContract) TransferHistory(ctx contractapi.TransactionContextInterfa
 		Date:		customTime,
 		Sender:		sen,
 	}
+	if fee > 0 {
+		his.Fee = strconv.Itoa(fee)
+	}
 	hisJSON, err := json.Marshal(his)
 	if err != nil {
 		return err
@@ -199,6 +299,36 @@ func (s *UserContract) TransferHistory(ctx contractapi.TransactionContextInterfa
 	return ctx.GetStub().PutState(id, hisJSON)
 }
 
+// transferHistories writes several history entries in one transaction.
+// TransferHistory numbers entries from the committed history, which does not
+// include writes of the same transaction, so batches must number them here.
+func (s *UserContract) transferHistories(ctx contractapi.TransactionContextInterface, entries []*AccountHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	history, err := s.ReadTransferHistory(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	for i, his := range entries {
+		his.ID = strconv.Itoa(len(history) + i + 1)
+		his.Date = now.Format("2006-01-02 15:04")
+		hisJSON, err := json.Marshal(his)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(his.ID, hisJSON)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *UserContract) ReadTransferHistory(ctx contractapi.TransactionContextInterface) ([]*AccountHistory, error) {
 	historyJSON, err := ctx.GetStub().GetStateByRange("0", "999")
 	if err != nil {
@@ -0,0 +1,342 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const (
+	tokenType        = "token"
+	spentTokenType   = "spentToken"
+	tokenHoldingType = "tokenHolding"
+	tokenIssueKind   = "tokenIssue"
+)
+
+// Token is an unspent output of the UTXO token model, which sits beside the account balances.
+// Owner is the client identity (GetClientIdentity().GetID()) that can spend it.
+// Spending a token deletes it, so concurrent transfers only conflict when they spend the same token
+// or change the tokenHolding of the same owner.
+type Token struct {
+	ID     string `json:"ID"`
+	Owner  string `json:"owner"`
+	Amount int    `json:"amount"`
+	Issuer string `json:"issuer"`
+}
+
+// TokenOutput is an output requested by TransferToken.
+type TokenOutput struct {
+	Owner  string `json:"owner"`
+	Amount int    `json:"amount"`
+}
+
+// spentToken remembers which transaction spent a token so a second spend fails with a clear error.
+type spentToken struct {
+	ID      string `json:"ID"`
+	Owner   string `json:"owner"`
+	SpentBy string `json:"spentBy"`
+}
+
+// tokenHolding is the total of an owner's unspent tokens. It is kept with every token created
+// or spent so the holding limit is checked without reading all the tokens of the owner.
+type tokenHolding struct {
+	Owner string `json:"owner"`
+	Total int    `json:"total"`
+}
+
+// tokenIssue is a token the regulatory chaincode committed with UpdateSendTokenBalance.
+type tokenIssue struct {
+	ID     string `json:"ID"`
+	BankID string `json:"bankID"`
+	Owner  string `json:"owner"`
+	Amount int    `json:"amount"`
+}
+
+// 은행에서 토큰 발행
+// IssueToken creates the token the regulatory chaincode committed with UpdateSendTokenBalance,
+// after debiting the bank. Each issue is created once.
+func (s *UserContract) IssueToken(ctx contractapi.TransactionContextInterface, issueID string) (*Token, error) {
+	payload, err := queryRegulatory(ctx, "ReadTokenIssue", issueID)
+	if err != nil {
+		return nil, err
+	}
+	var issue tokenIssue
+	err = json.Unmarshal(payload, &issue)
+	if err != nil {
+		return nil, err
+	}
+	err = s.markApplied(ctx, tokenIssueKind, issueID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.screenAccounts(ctx, &UserAccount{ID: issue.Owner})
+	if err != nil {
+		return nil, err
+	}
+	err = s.addTokenHoldings(ctx, map[string]int{issue.Owner: issue.Amount})
+	if err != nil {
+		return nil, err
+	}
+
+	token := Token{
+		ID:     tokenID(ctx, 0),
+		Owner:  issue.Owner,
+		Amount: issue.Amount,
+		Issuer: issue.BankID,
+	}
+	err = s.putToken(ctx, &token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TransferToken spends the caller's tokens inputs and creates outputs of the same total.
+// Change goes back to the caller as an output it owns.
+func (s *UserContract) TransferToken(ctx contractapi.TransactionContextInterface, inputs []string, outputs []*TokenOutput) ([]*Token, error) {
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, err
+	}
+	spent, issuer, err := s.spendTokens(ctx, owner, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) == 0 {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "at least one output is required")
+	}
+
+	total := 0
+	for _, output := range outputs {
+		if output.Owner == "" || output.Amount <= 0 {
+			return nil, cbdcerr.New(cbdcerr.InvalidArgument, "each output needs an owner and a positive amount")
+		}
+		total = total + output.Amount
+	}
+	if total != spent {
+		return nil, cbdcerr.New(cbdcerr.InvalidArgument, "outputs total %d but inputs total %d", total, spent)
+	}
+
+	paid := make(map[string]int)
+	var receivers []string
+	for _, output := range outputs {
+		if output.Owner == owner {
+			continue
+		}
+		if _, ok := paid[output.Owner]; !ok {
+			receivers = append(receivers, output.Owner)
+		}
+		paid[output.Owner] = paid[output.Owner] + output.Amount
+	}
+	for _, rec := range receivers {
+		err = s.screenAccounts(ctx, &UserAccount{ID: owner}, &UserAccount{ID: rec})
+		if err != nil {
+			return nil, err
+		}
+		err = s.screenTransfer(ctx, owner, rec, paid[rec])
+		if err != nil {
+			return nil, err
+		}
+	}
+	holdings := map[string]int{owner: -spent}
+	for _, output := range outputs {
+		holdings[output.Owner] = holdings[output.Owner] + output.Amount
+	}
+	err = s.addTokenHoldings(ctx, holdings)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*Token
+	for i, output := range outputs {
+		token := Token{
+			ID:     tokenID(ctx, i),
+			Owner:  output.Owner,
+			Amount: output.Amount,
+			Issuer: issuer,
+		}
+		err = s.putToken(ctx, &token)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+// RedeemToken spends the caller's tokens inputs and returns their value to the bank bankID,
+// which the regulatory chaincode credits with SettleBankReceivable.
+func (s *UserContract) RedeemToken(ctx contractapi.TransactionContextInterface, bankID string, inputs []string) (int, error) {
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, err
+	}
+	payload, err := queryRegulatory(ctx, "AccountExist", bankID)
+	if err != nil {
+		return 0, err
+	}
+	if string(payload) != "true" {
+		return 0, cbdcerr.New(cbdcerr.NotFound, "the bank %s does not exist", bankID)
+	}
+	spent, _, err := s.spendTokens(ctx, owner, inputs)
+	if err != nil {
+		return 0, err
+	}
+	err = s.addTokenHoldings(ctx, map[string]int{owner: -spent})
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.oweBank(ctx, bankID, spent)
+	if err != nil {
+		return 0, err
+	}
+	return spent, nil
+}
+
+// ReadTokens returns the unspent tokens of owner.
+func (s *UserContract) ReadTokens(ctx contractapi.TransactionContextInterface, owner string) ([]*Token, error) {
+	tokenJSON, err := ctx.GetStub().GetStateByPartialCompositeKey(tokenType, []string{owner})
+	if err != nil {
+		return nil, err
+	}
+	defer tokenJSON.Close()
+	var tokens []*Token
+	for tokenJSON.HasNext() {
+		queryResponse, err := tokenJSON.Next()
+		if err != nil {
+			return nil, err
+		}
+		var token Token
+		err = json.Unmarshal(queryResponse.Value, &token)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+// ReadTokenBalance returns the total of owner's unspent tokens.
+func (s *UserContract) ReadTokenBalance(ctx contractapi.TransactionContextInterface, owner string) (int, error) {
+	var holding tokenHolding
+	found, err := s.getRecord(ctx, tokenHoldingType, owner, &holding)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return holding.Total, nil
+	}
+
+	// 합계 기록 전에 만들어진 토큰은 직접 합산
+	tokens, err := s.ReadTokens(ctx, owner)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, token := range tokens {
+		total = total + token.Amount
+	}
+	return total, nil
+}
+
+// spendTokens deletes owner's tokens ids and returns their total and the issuer of the first one.
+func (s *UserContract) spendTokens(ctx contractapi.TransactionContextInterface, owner string, ids []string) (int, string, error) {
+	if len(ids) == 0 {
+		return 0, "", cbdcerr.New(cbdcerr.InvalidArgument, "at least one input is required")
+	}
+	seen := make(map[string]bool)
+	total := 0
+	issuer := ""
+	for _, id := range ids {
+		if seen[id] {
+			return 0, "", cbdcerr.New(cbdcerr.InvalidArgument, "the token %s is spent twice", id)
+		}
+		seen[id] = true
+
+		var spent spentToken
+		found, err := s.getRecord(ctx, spentTokenType, id, &spent)
+		if err != nil {
+			return 0, "", err
+		}
+		if found {
+			return 0, "", cbdcerr.New(cbdcerr.DoubleSpend, "the token %s was already spent by %s", id, spent.SpentBy)
+		}
+		key, err := ctx.GetStub().CreateCompositeKey(tokenType, []string{owner, id})
+		if err != nil {
+			return 0, "", err
+		}
+		tokenJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to read world state: %v", err)
+		}
+		if tokenJSON == nil {
+			return 0, "", cbdcerr.New(cbdcerr.NotFound, "the token %s does not exist or is not yours", id)
+		}
+		var token Token
+		err = json.Unmarshal(tokenJSON, &token)
+		if err != nil {
+			return 0, "", err
+		}
+
+		err = ctx.GetStub().DelState(key)
+		if err != nil {
+			return 0, "", err
+		}
+		err = s.putRecord(ctx, spentTokenType, id, spentToken{ID: id, Owner: owner, SpentBy: ctx.GetStub().GetTxID()})
+		if err != nil {
+			return 0, "", err
+		}
+		if issuer == "" {
+			issuer = token.Issuer
+		}
+		total = total + token.Amount
+	}
+	return total, issuer, nil
+}
+
+// addTokenHoldings adds the change in each owner's token total, once per owner, and fails
+// if it would take an owner receiving tokens over MAX_VAL.
+func (s *UserContract) addTokenHoldings(ctx contractapi.TransactionContextInterface, changes map[string]int) error {
+	owners := make([]string, 0, len(changes))
+	for owner := range changes {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		balance, err := s.ReadTokenBalance(ctx, owner)
+		if err != nil {
+			return err
+		}
+		total := balance + changes[owner]
+		if changes[owner] > 0 && total > MAX_VAL {
+			return cbdcerr.New(cbdcerr.LimitExceeded, "Individuals cannot own more than %d in CBDC.", MAX_VAL)
+		}
+		err = s.putRecord(ctx, tokenHoldingType, owner, tokenHolding{Owner: owner, Total: total})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *UserContract) putToken(ctx contractapi.TransactionContextInterface, token *Token) error {
+	key, err := ctx.GetStub().CreateCompositeKey(tokenType, []string{token.Owner, token.ID})
+	if err != nil {
+		return err
+	}
+	tokenJSON, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, tokenJSON)
+}
+
+// tokenID names the i-th output of the transaction.
+func tokenID(ctx contractapi.TransactionContextInterface, i int) string {
+	return ctx.GetStub().GetTxID() + ":" + strconv.Itoa(i)
+}
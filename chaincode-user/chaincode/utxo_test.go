@@ -0,0 +1,88 @@
+package chaincode_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, nil)
+	alice, _ := owner("User1").GetID()
+	bob, _ := owner("User2").GetID()
+	screen := screening()
+	l.peer("regulatorychaincode", "regulatory-channel", func(args []string) peer.Response {
+		switch {
+		case args[0] == "ReadTokenIssue" && args[1] == "issue1":
+			return shim.Success([]byte(`{"ID":"issue1","bankID":"Bank1","owner":"` + alice + `","amount":600}`))
+		case args[0] == "ReadTokenIssue" && args[1] == "issue2":
+			return shim.Success([]byte(`{"ID":"issue2","bankID":"Bank1","owner":"` + bob + `","amount":600}`))
+		case args[0] == "AccountExist" && args[1] == "Bank9":
+			return shim.Success([]byte("false"))
+		}
+		return screen(args)
+	})
+	balance := func(owner string) int {
+		var total int
+		require.NoError(t, l.tx(regulator, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			total, err = s.ReadTokenBalance(ctx, owner)
+			return err
+		}))
+		return total
+	}
+	issue := func(issueID string) (*chaincode.Token, error) {
+		var token *chaincode.Token
+		err := l.tx(operator("Bank1"), func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			token, err = s.IssueToken(ctx, issueID)
+			return err
+		})
+		return token, err
+	}
+	transfer := func(inputs []string, outputs ...*chaincode.TokenOutput) ([]*chaincode.Token, error) {
+		var tokens []*chaincode.Token
+		err := l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			tokens, err = s.TransferToken(ctx, inputs, outputs)
+			return err
+		})
+		return tokens, err
+	}
+	redeem := func(bankID string, inputs ...string) error {
+		return l.tx(owner("User2"), func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.RedeemToken(ctx, bankID, inputs)
+			return err
+		})
+	}
+
+	minted, err := issue("issue1")
+	require.NoError(t, err)
+	require.Equal(t, 600, balance(alice))
+
+	tokens, err := transfer([]string{minted.ID}, &chaincode.TokenOutput{Owner: bob, Amount: 500}, &chaincode.TokenOutput{Owner: alice, Amount: 100})
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, 100, balance(alice))
+	require.Equal(t, 500, balance(bob))
+
+	_, err = transfer([]string{minted.ID}, &chaincode.TokenOutput{Owner: bob, Amount: 600})
+	requireCode(t, err, cbdcerr.DoubleSpend, "the token "+minted.ID+" was already spent by "+strings.TrimSuffix(tokens[0].ID, ":0"))
+
+	// 보유 한도는 토큰 합계 기록으로 확인
+	_, err = issue("issue2")
+	requireCode(t, err, cbdcerr.LimitExceeded, "Individuals cannot own more than 1000 in CBDC.")
+	require.Equal(t, 500, balance(bob))
+
+	requireCode(t, redeem("Bank9", tokens[0].ID), cbdcerr.NotFound, "the bank Bank9 does not exist")
+	require.NoError(t, redeem("Bank1", tokens[0].ID))
+	require.Equal(t, 0, balance(bob))
+	require.Equal(t, 500, receivable(l, "Bank1"))
+}
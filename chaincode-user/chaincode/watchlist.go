@@ -0,0 +1,246 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+)
+
+const watchListMatchType = "watchListMatch"
+
+// WatchListMatch is the record of an operation that screened a party on the watch list,
+// stored under its tx ID.
+type WatchListMatch struct {
+	TxID     string   `json:"txID"`
+	Function string   `json:"function"`
+	Matches  []string `json:"matches"`
+	Version  int      `json:"version"`
+	Date     string   `json:"date"`
+}
+
+// txMatches holds the watch-list match of each running transaction, by tx ID.
+var txMatches = struct {
+	sync.Mutex
+	m map[string]*WatchListMatch
+}{m: make(map[string]*WatchListMatch)}
+
+// watchListMatch is the screening result returned by the regulatory chaincode.
+type watchListMatch struct {
+	Matches []string `json:"matches"`
+	Version int      `json:"version"`
+}
+
+// watchListHash hashes an identifier the same way the regulator hashes its watch list.
+func watchListHash(id string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(id)), " ")
+	digest := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(digest[:])
+}
+
+// screenAccounts checks the ID and name of each account against the regulator's watch list.
+// Only hashes are sent to the regulatory channel. A match stores a WatchListMatch record,
+// emits a WatchListMatch event and fails the operation with a Blocked error naming the
+// matched hashes, which the WatchList wrapper commits as a refusal.
+func (s *UserContract) screenAccounts(ctx contractapi.TransactionContextInterface, accounts ...*UserAccount) error {
+	var hashes []string
+	for _, account := range accounts {
+		hashes = append(hashes, watchListHash(account.ID))
+		nameHash, err := s.accountNameHash(ctx, account)
+		if err != nil {
+			return err
+		}
+		if nameHash != "" {
+			hashes = append(hashes, nameHash)
+		}
+	}
+	hashesJSON, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+
+	params := []string{"CheckWatchList", string(hashesJSON)}
+	queryArgs := make([][]byte, len(params))
+
+	for i, arg := range params {
+		queryArgs[i] = []byte(arg)
+	}
+	response := ctx.GetStub().InvokeChaincode("regulatorychaincode", queryArgs, "regulatory-channel")
+	if response.Status != 200 {
+		return cbdcerr.FromResponse(response)
+	}
+	var match watchListMatch
+	err = json.Unmarshal(response.Payload, &match)
+	if err != nil {
+		return err
+	}
+	if len(match.Matches) == 0 {
+		return nil
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	// 한 거래에서 여러 번 일치하면 기록 하나에 모음
+	txID := ctx.GetStub().GetTxID()
+	txMatches.Lock()
+	record, ok := txMatches.m[txID]
+	if !ok {
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		record = &WatchListMatch{TxID: txID, Function: function, Version: match.Version, Date: now.Format("2006-01-02 15:04")}
+		txMatches.m[txID] = record
+	}
+	record.Matches = append(record.Matches, match.Matches...)
+	txMatches.Unlock()
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	err = s.putRecord(ctx, watchListMatchType, record.TxID, record)
+	if err != nil {
+		return err
+	}
+	err = emitEvent(ctx, "WatchListMatch", recordJSON)
+	if err != nil {
+		return err
+	}
+	return cbdcerr.New(cbdcerr.Blocked, "operation blocked: a party is on the watch list (version %d): %s", match.Version, strings.Join(match.Matches, ","))
+}
+
+// ReadWatchListMatch returns the watch-list match recorded by a transaction.
+func (s *UserContract) ReadWatchListMatch(ctx contractapi.TransactionContextInterface, txID string) (*WatchListMatch, error) {
+	if !isRegulator(ctx) {
+		return nil, cbdcerr.New(cbdcerr.Unauthorized, "only the regulator can read watch-list matches")
+	}
+	var match WatchListMatch
+	found, err := s.getRecord(ctx, watchListMatchType, txID, &match)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, cbdcerr.New(cbdcerr.NotFound, "the transaction %s has no watch-list match", txID)
+	}
+	return &match, nil
+}
+
+// bufferedStub holds back the writes and the event of a transaction until it is flushed.
+// Reads go to the stub, which does not see the transaction's own writes either.
+type bufferedStub struct {
+	shim.ChaincodeStubInterface
+	writes []func() error
+}
+
+func (b *bufferedStub) PutState(key string, value []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.PutState(key, value) })
+	return nil
+}
+
+func (b *bufferedStub) DelState(key string) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.DelState(key) })
+	return nil
+}
+
+func (b *bufferedStub) SetStateValidationParameter(key string, ep []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.SetStateValidationParameter(key, ep) })
+	return nil
+}
+
+func (b *bufferedStub) PutPrivateData(collection string, key string, value []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.PutPrivateData(collection, key, value) })
+	return nil
+}
+
+func (b *bufferedStub) DelPrivateData(collection string, key string) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.DelPrivateData(collection, key) })
+	return nil
+}
+
+func (b *bufferedStub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	b.writes = append(b.writes, func() error {
+		return b.ChaincodeStubInterface.SetPrivateDataValidationParameter(collection, key, ep)
+	})
+	return nil
+}
+
+func (b *bufferedStub) SetEvent(name string, payload []byte) error {
+	b.writes = append(b.writes, func() error { return b.ChaincodeStubInterface.SetEvent(name, payload) })
+	return nil
+}
+
+// flush makes the held back writes, in order.
+func (b *bufferedStub) flush() error {
+	for _, write := range b.writes {
+		err := write()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type watchListChaincode struct {
+	cc shim.Chaincode
+}
+
+// WatchList wraps the contract chaincode so that an operation blocked by the watch list is
+// committed as a refusal: the writes the operation made are dropped, its WatchListMatch
+// record and event are kept, and its Blocked error is returned with status
+// cbdcerr.Refused so that the peers endorse the transaction.
+func WatchList(cc shim.Chaincode) shim.Chaincode {
+	return &watchListChaincode{cc: cc}
+}
+
+func (w *watchListChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return w.cc.Init(stub)
+}
+
+func (w *watchListChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	txID := stub.GetTxID()
+	defer func() {
+		txMatches.Lock()
+		delete(txMatches.m, txID)
+		txMatches.Unlock()
+	}()
+
+	buffered := &bufferedStub{ChaincodeStubInterface: stub}
+	response := w.cc.Invoke(buffered)
+	if response.Status < shim.ERRORTHRESHOLD {
+		err := buffered.flush()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return response
+	}
+
+	txMatches.Lock()
+	match := txMatches.m[txID]
+	txMatches.Unlock()
+	refusal := cbdcerr.Parse(response.Message)
+	if match == nil || refusal.Code != cbdcerr.Blocked {
+		return response
+	}
+	key, err := stub.CreateCompositeKey(watchListMatchType, []string{txID})
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	matchJSON, err := json.Marshal(match)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.PutState(key, matchJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	err = stub.SetEvent("WatchListMatch", matchJSON)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return cbdcerr.Refusal(refusal, matchJSON)
+}
@@ -0,0 +1,84 @@
+package chaincode_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/cbdcerr"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func hashed(id string) string {
+	digest := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(digest[:])
+}
+
+// watchList is the regulatory chaincode with the listed IDs on version 3 of its watch list.
+func watchList(listed ...string) peerChaincode {
+	screen := screening()
+	return func(args []string) peer.Response {
+		if args[0] != "CheckWatchList" {
+			return screen(args)
+		}
+		var hashes []string
+		if err := json.Unmarshal([]byte(args[1]), &hashes); err != nil {
+			return shim.Error(err.Error())
+		}
+		matches := []string{}
+		for _, hash := range hashes {
+			for _, id := range listed {
+				if hash == hashed(id) {
+					matches = append(matches, hash)
+				}
+			}
+		}
+		matchJSON, err := json.Marshal(map[string]interface{}{"version": 3, "matches": matches})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(matchJSON)
+	}
+}
+
+func TestWatchListMatch(t *testing.T) {
+	s := chaincode.UserContract{}
+	l := newLedger(t, map[string]int{"User1": 300, "User2": 0, "User3": 0})
+	l.peer("regulatorychaincode", "regulatory-channel", watchList("user2"))
+	pay := func(rec string) error {
+		return l.tx(owner("User1"), func(ctx contractapi.TransactionContextInterface) error {
+			return s.TransferBalanceUser(ctx, "Bank1", "User1", rec, 100)
+		})
+	}
+
+	// 차단된 거래도 일치 기록과 이벤트는 커밋
+	requireCode(t, pay("User2"), cbdcerr.Blocked, "operation blocked: a party is on the watch list (version 3): "+hashed("user2"))
+	txID := fmt.Sprintf("%s/tx%03d", t.Name(), l.n)
+	require.Equal(t, 300, l.balance("User1"))
+	require.Equal(t, 0, l.balance("User2"))
+	require.Equal(t, "WatchListMatch", l.lastEvent().EventName)
+
+	read := func(c client) (*chaincode.WatchListMatch, error) {
+		var match *chaincode.WatchListMatch
+		err := l.tx(c, func(ctx contractapi.TransactionContextInterface) error {
+			var err error
+			match, err = s.ReadWatchListMatch(ctx, txID)
+			return err
+		})
+		return match, err
+	}
+	_, err := read(owner("User1"))
+	requireCode(t, err, cbdcerr.Unauthorized, "only the regulator can read watch-list matches")
+	match, err := read(regulator)
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.WatchListMatch{TxID: txID, Matches: []string{hashed("user2")}, Version: 3, Date: "2026-10-01 09:00"}, match)
+
+	require.NoError(t, pay("User3"))
+	require.Equal(t, 200, l.balance("User1"))
+}
@@ -0,0 +1,582 @@
+// Package confidential implements the Pedersen commitments and range proofs
+// behind confidential balances.
+//
+// A commitment to a value v with blinding factor r is v*G + r*H on P-256,
+// where H is derived from a fixed seed so that nobody knows its discrete log
+// relative to G. Commitments add up homomorphically, so the chaincode can move
+// value between accounts without learning the amounts.
+//
+// A range proof shows that a commitment opens to a value in [0, 2^Bits) without
+// revealing it. The value is split into bit commitments, each proven to hold
+// 0 or 1 with a Fiat-Shamir OR proof, and the bit commitments weighted by
+// powers of two must add up to the original commitment.
+//
+// Openings can be sealed to a P-256 public key so that the receiver of a
+// transfer and the regulator, who holds the audit key, can learn the amount.
+// Next to the sealed opening, the amount is ElGamal encrypted to the key as
+// v*G + k*P, k*G, with a proof that it is the amount of the commitment, so the
+// chaincode can check what the key holder will read without opening it.
+package confidential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Bits is the size of the range proven by a RangeProof.
+const Bits = 32
+
+var (
+	curve = elliptic.P256()
+	order = curve.Params().N
+
+	// H is the second generator of the commitments.
+	H = hashToPoint("CBDC pedersen generator H")
+)
+
+// Point is a point of P-256. The zero Point is the point at infinity.
+type Point struct {
+	X, Y *big.Int
+}
+
+func (p *Point) isInfinity() bool {
+	return p.X == nil || (p.X.Sign() == 0 && p.Y.Sign() == 0)
+}
+
+// String returns the hex encoded uncompressed point, or "" for the point at infinity.
+func (p *Point) String() string {
+	if p.isInfinity() {
+		return ""
+	}
+	return hex.EncodeToString(elliptic.Marshal(curve, p.X, p.Y))
+}
+
+// Equal reports whether p and q are the same point.
+func (p *Point) Equal(q *Point) bool {
+	return p.String() == q.String()
+}
+
+// ParsePoint decodes a point encoded by String.
+func ParsePoint(s string) (*Point, error) {
+	if s == "" {
+		return &Point{}, nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("confidential: invalid point: %v", err)
+	}
+	x, y := elliptic.Unmarshal(curve, b)
+	if x == nil {
+		return nil, errors.New("confidential: invalid point")
+	}
+	return &Point{X: x, Y: y}, nil
+}
+
+// Add returns p + q.
+func (p *Point) Add(q *Point) *Point {
+	switch {
+	case p.isInfinity():
+		return q
+	case q.isInfinity():
+		return p
+	case p.X.Cmp(q.X) == 0 && p.Y.Cmp(q.Y) != 0:
+		return &Point{}
+	case p.X.Cmp(q.X) == 0:
+		x, y := curve.Double(p.X, p.Y)
+		return &Point{X: x, Y: y}
+	}
+	x, y := curve.Add(p.X, p.Y, q.X, q.Y)
+	return &Point{X: x, Y: y}
+}
+
+// Neg returns -p.
+func (p *Point) Neg() *Point {
+	if p.isInfinity() {
+		return p
+	}
+	return &Point{X: new(big.Int).Set(p.X), Y: new(big.Int).Sub(curve.Params().P, p.Y)}
+}
+
+// Sub returns p - q.
+func (p *Point) Sub(q *Point) *Point {
+	return p.Add(q.Neg())
+}
+
+// Mul returns k*p.
+func (p *Point) Mul(k *big.Int) *Point {
+	k = new(big.Int).Mod(k, order)
+	if p.isInfinity() || k.Sign() == 0 {
+		return &Point{}
+	}
+	x, y := curve.ScalarMult(p.X, p.Y, k.Bytes())
+	return &Point{X: x, Y: y}
+}
+
+func baseMul(k *big.Int) *Point {
+	k = new(big.Int).Mod(k, order)
+	if k.Sign() == 0 {
+		return &Point{}
+	}
+	x, y := curve.ScalarBaseMult(k.Bytes())
+	return &Point{X: x, Y: y}
+}
+
+// hashToPoint maps seed to a point by hashing it with a counter until the
+// digest is the x coordinate of a point on the curve.
+func hashToPoint(seed string) *Point {
+	params := curve.Params()
+	three := big.NewInt(3)
+	for i := 0; ; i++ {
+		digest := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", seed, i)))
+		x := new(big.Int).SetBytes(digest[:])
+		if x.Cmp(params.P) >= 0 {
+			continue
+		}
+		// y² = x³ - 3x + b
+		y2 := new(big.Int).Exp(x, three, params.P)
+		y2.Sub(y2, new(big.Int).Mul(three, x))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+		y := new(big.Int).ModSqrt(y2, params.P)
+		if y == nil {
+			continue
+		}
+		return &Point{X: x, Y: y}
+	}
+}
+
+// Commit returns v*G + r*H.
+func Commit(v uint64, r *big.Int) *Point {
+	return baseMul(new(big.Int).SetUint64(v)).Add(H.Mul(r))
+}
+
+// CommitPublic returns the commitment to a public amount, v*G with no blinding.
+func CommitPublic(v int) *Point {
+	return baseMul(big.NewInt(int64(v)))
+}
+
+// RandomScalar returns a uniformly random blinding factor.
+func RandomScalar(rand io.Reader) (*big.Int, error) {
+	for {
+		b := make([]byte, 32)
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		k := new(big.Int).SetBytes(b)
+		if k.Sign() > 0 && k.Cmp(order) < 0 {
+			return k, nil
+		}
+	}
+}
+
+// bitProof proves that C commits to 0 or 1.
+type bitProof struct {
+	C  string `json:"c"`
+	E0 string `json:"e0"`
+	E1 string `json:"e1"`
+	S0 string `json:"s0"`
+	S1 string `json:"s1"`
+}
+
+// RangeProof proves that a commitment opens to a value in [0, 2^Bits).
+type RangeProof struct {
+	Bits []bitProof `json:"bits"`
+}
+
+// ProveRange proves that Commit(v, r) opens to a value in range.
+func ProveRange(v uint64, r *big.Int, rand io.Reader) (*RangeProof, error) {
+	if v>>Bits != 0 {
+		return nil, fmt.Errorf("confidential: %d is out of range", v)
+	}
+	commitment := Commit(v, r)
+
+	// the blinding factors of the bits must add up to r once weighted by 2^i
+	blinds := make([]*big.Int, Bits)
+	rest := new(big.Int).Set(r)
+	for i := 0; i < Bits-1; i++ {
+		b, err := RandomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		blinds[i] = b
+		rest.Sub(rest, new(big.Int).Lsh(b, uint(i)))
+	}
+	inv := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), Bits-1), order)
+	blinds[Bits-1] = rest.Mul(rest, inv).Mod(rest, order)
+
+	proof := RangeProof{Bits: make([]bitProof, Bits)}
+	for i := 0; i < Bits; i++ {
+		bit := int(v >> uint(i) & 1)
+		bp, err := proveBit(commitment, i, bit, blinds[i], rand)
+		if err != nil {
+			return nil, err
+		}
+		proof.Bits[i] = *bp
+	}
+	return &proof, nil
+}
+
+// proveBit builds the OR proof that C = r*H (bit 0) or C - G = r*H (bit 1),
+// simulating the branch that does not hold.
+func proveBit(commitment *Point, i int, bit int, r *big.Int, rand io.Reader) (*bitProof, error) {
+	c := Commit(uint64(bit), r)
+	statements := []*Point{c, c.Sub(baseMul(big.NewInt(1)))}
+
+	k, err := RandomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	fakeE, err := RandomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	fakeS, err := RandomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	announce := make([]*Point, 2)
+	announce[bit] = H.Mul(k)
+	announce[1-bit] = H.Mul(fakeS).Sub(statements[1-bit].Mul(fakeE))
+
+	e := challenge(commitment, i, c, announce[0], announce[1])
+	realE := new(big.Int).Sub(e, fakeE)
+	realE.Mod(realE, order)
+	realS := new(big.Int).Mul(realE, r)
+	realS.Add(realS, k).Mod(realS, order)
+
+	es := []*big.Int{nil, nil}
+	ss := []*big.Int{nil, nil}
+	es[bit], ss[bit] = realE, realS
+	es[1-bit], ss[1-bit] = fakeE, fakeS
+	return &bitProof{
+		C:  c.String(),
+		E0: hex.EncodeToString(es[0].Bytes()),
+		E1: hex.EncodeToString(es[1].Bytes()),
+		S0: hex.EncodeToString(ss[0].Bytes()),
+		S1: hex.EncodeToString(ss[1].Bytes()),
+	}, nil
+}
+
+// challenge returns the Fiat-Shamir challenge of bit i, bound to the proven commitment.
+func challenge(commitment *Point, i int, c, a0, a1 *Point) *big.Int {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%s|%s|%s", commitment.String(), i, c.String(), a0.String(), a1.String())
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, order)
+}
+
+// Verify checks that the proof holds for commitment.
+func (p *RangeProof) Verify(commitment *Point) error {
+	if len(p.Bits) != Bits {
+		return fmt.Errorf("confidential: range proof must have %d bits", Bits)
+	}
+	sum := &Point{}
+	for i, bp := range p.Bits {
+		c, err := ParsePoint(bp.C)
+		if err != nil {
+			return err
+		}
+		scalars := make([]*big.Int, 4)
+		for j, s := range []string{bp.E0, bp.E1, bp.S0, bp.S1} {
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return fmt.Errorf("confidential: invalid range proof: %v", err)
+			}
+			scalars[j] = new(big.Int).SetBytes(b)
+		}
+		e0, e1, s0, s1 := scalars[0], scalars[1], scalars[2], scalars[3]
+
+		a0 := H.Mul(s0).Sub(c.Mul(e0))
+		a1 := H.Mul(s1).Sub(c.Sub(baseMul(big.NewInt(1))).Mul(e1))
+		e := challenge(commitment, i, c, a0, a1)
+		if new(big.Int).Mod(new(big.Int).Add(e0, e1), order).Cmp(e) != 0 {
+			return fmt.Errorf("confidential: bit %d of the range proof is invalid", i)
+		}
+		sum = sum.Add(c.Mul(new(big.Int).Lsh(big.NewInt(1), uint(i))))
+	}
+	if !sum.Equal(commitment) {
+		return errors.New("confidential: range proof does not match the commitment")
+	}
+	return nil
+}
+
+// Marshal encodes the proof as it is passed to the chaincode.
+func (p *RangeProof) Marshal() (string, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ParseRangeProof decodes a proof encoded by Marshal.
+func ParseRangeProof(s string) (*RangeProof, error) {
+	var p RangeProof
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, fmt.Errorf("confidential: invalid range proof: %v", err)
+	}
+	return &p, nil
+}
+
+// Opening is the value and blinding factor of a commitment.
+type Opening struct {
+	Value uint64 `json:"v"`
+	Blind string `json:"r"`
+}
+
+// Commitment returns the commitment the opening opens.
+func (o *Opening) Commitment() (*Point, error) {
+	r, ok := new(big.Int).SetString(o.Blind, 16)
+	if !ok {
+		return nil, errors.New("confidential: invalid blinding factor")
+	}
+	return Commit(o.Value, r), nil
+}
+
+// sealedOpening is an opening sealed to a key, along with its amount and blinding factor
+// encrypted to the same key and the proof that they are the ones of the commitment.
+type sealedOpening struct {
+	Box string `json:"box"`
+	E1  string `json:"e1"`
+	E2  string `json:"e2"`
+	E3  string `json:"e3"`
+	T1  string `json:"t1"`
+	T2  string `json:"t2"`
+	T3  string `json:"t3"`
+	T4  string `json:"t4"`
+	Z1  string `json:"z1"`
+	Z2  string `json:"z2"`
+	Z3  string `json:"z3"`
+}
+
+// Seal encrypts o to pub with ECIES, an ephemeral P-256 key agreement and AES-GCM,
+// and adds the proven encryption of its amount and blinding factor checked by VerifySealed.
+func Seal(pub *ecdsa.PublicKey, o *Opening, rand io.Reader) (string, error) {
+	if o.Value>>Bits != 0 {
+		return "", fmt.Errorf("confidential: %d is out of range", o.Value)
+	}
+	r, ok := new(big.Int).SetString(o.Blind, 16)
+	if !ok {
+		return "", errors.New("confidential: invalid blinding factor")
+	}
+	box, err := sealBox(pub, o, rand)
+	if err != nil {
+		return "", err
+	}
+
+	// E1 = k*G, E2 = v*G + k*P, E3 = r*G + k*P, proven with the commitment C = v*G + r*H
+	p := &Point{X: pub.X, Y: pub.Y}
+	v := new(big.Int).SetUint64(o.Value)
+	scalars := make([]*big.Int, 4)
+	for i := range scalars {
+		if scalars[i], err = RandomScalar(rand); err != nil {
+			return "", err
+		}
+	}
+	k, a, b, c := scalars[0], scalars[1], scalars[2], scalars[3]
+	commitment := Commit(o.Value, r)
+	e1 := baseMul(k)
+	e2 := baseMul(v).Add(p.Mul(k))
+	e3 := baseMul(r).Add(p.Mul(k))
+	t1 := baseMul(a).Add(H.Mul(b))
+	t2 := baseMul(c)
+	t3 := baseMul(a).Add(p.Mul(c))
+	t4 := baseMul(b).Add(p.Mul(c))
+	e := sealChallenge(p, commitment, e1, e2, e3, t1, t2, t3, t4)
+	z := func(blind, secret *big.Int) string {
+		s := new(big.Int).Mul(e, secret)
+		s.Add(s, blind).Mod(s, order)
+		return hex.EncodeToString(s.Bytes())
+	}
+	sealed, err := json.Marshal(sealedOpening{
+		Box: box,
+		E1:  e1.String(),
+		E2:  e2.String(),
+		E3:  e3.String(),
+		T1:  t1.String(),
+		T2:  t2.String(),
+		T3:  t3.String(),
+		T4:  t4.String(),
+		Z1:  z(a, v),
+		Z2:  z(b, r),
+		Z3:  z(c, k),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(sealed), nil
+}
+
+// VerifySealed checks that the amount and the blinding factor encrypted to pub in sealed
+// are the ones of commitment. The opening itself is only in the sealed box, which Open
+// checks against both.
+func VerifySealed(pub *ecdsa.PublicKey, sealed string, commitment *Point) error {
+	s, points, err := parseSealed(sealed)
+	if err != nil {
+		return err
+	}
+	zs := make([]*big.Int, 3)
+	for i, z := range []string{s.Z1, s.Z2, s.Z3} {
+		b, err := hex.DecodeString(z)
+		if err != nil {
+			return fmt.Errorf("confidential: invalid sealed opening: %v", err)
+		}
+		zs[i] = new(big.Int).SetBytes(b)
+	}
+	p := &Point{X: pub.X, Y: pub.Y}
+	e1, e2, e3, t1, t2, t3, t4 := points[0], points[1], points[2], points[3], points[4], points[5], points[6]
+	e := sealChallenge(p, commitment, e1, e2, e3, t1, t2, t3, t4)
+	if !baseMul(zs[0]).Add(H.Mul(zs[1])).Equal(t1.Add(commitment.Mul(e))) ||
+		!baseMul(zs[2]).Equal(t2.Add(e1.Mul(e))) ||
+		!baseMul(zs[0]).Add(p.Mul(zs[2])).Equal(t3.Add(e2.Mul(e))) ||
+		!baseMul(zs[1]).Add(p.Mul(zs[2])).Equal(t4.Add(e3.Mul(e))) {
+		return errors.New("confidential: sealed amount does not match the commitment")
+	}
+	return nil
+}
+
+func parseSealed(sealed string) (*sealedOpening, []*Point, error) {
+	var s sealedOpening
+	if err := json.Unmarshal([]byte(sealed), &s); err != nil {
+		return nil, nil, fmt.Errorf("confidential: invalid sealed opening: %v", err)
+	}
+	points := make([]*Point, 7)
+	for i, encoded := range []string{s.E1, s.E2, s.E3, s.T1, s.T2, s.T3, s.T4} {
+		point, err := ParsePoint(encoded)
+		if err != nil {
+			return nil, nil, err
+		}
+		points[i] = point
+	}
+	return &s, points, nil
+}
+
+// sealChallenge returns the Fiat-Shamir challenge of a sealed amount and blinding factor.
+func sealChallenge(p, commitment, e1, e2, e3, t1, t2, t3, t4 *Point) *big.Int {
+	h := sha256.New()
+	fmt.Fprintf(h, "seal|%s|%s|%s|%s|%s|%s|%s|%s|%s", p.String(), commitment.String(), e1.String(), e2.String(), e3.String(), t1.String(), t2.String(), t3.String(), t4.String())
+	e := new(big.Int).SetBytes(h.Sum(nil))
+	return e.Mod(e, order)
+}
+
+func sealBox(pub *ecdsa.PublicKey, o *Opening, rand io.Reader) (string, error) {
+	eph, err := ecdsa.GenerateKey(curve, rand)
+	if err != nil {
+		return "", err
+	}
+	aead, err := sealKey(pub.X, pub.Y, eph.D)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return "", err
+	}
+	plain, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+	out := elliptic.Marshal(curve, eph.X, eph.Y)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, plain, nil)
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts an opening sealed to priv. It fails if the opening is not for the
+// amount and blinding factor encrypted next to it, which VerifySealed bound to the
+// commitment.
+func Open(priv *ecdsa.PrivateKey, sealed string) (*Opening, error) {
+	s, points, err := parseSealed(sealed)
+	if err != nil {
+		return nil, err
+	}
+	o, err := openBox(priv, s.Box)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := new(big.Int).SetString(o.Blind, 16)
+	if !ok {
+		return nil, errors.New("confidential: invalid blinding factor")
+	}
+	// E2 - d*E1 = v*G, E3 - d*E1 = r*G
+	shared := points[0].Mul(priv.D)
+	if !points[1].Sub(shared).Equal(baseMul(new(big.Int).SetUint64(o.Value))) ||
+		!points[2].Sub(shared).Equal(baseMul(r)) {
+		return nil, errors.New("confidential: sealed opening does not match its amount and blinding factor")
+	}
+	return o, nil
+}
+
+func openBox(priv *ecdsa.PrivateKey, box string) (*Opening, error) {
+	b, err := base64.RawURLEncoding.DecodeString(box)
+	if err != nil {
+		return nil, fmt.Errorf("confidential: invalid ciphertext: %v", err)
+	}
+	pointLen := (curve.Params().BitSize+7)/8*2 + 1
+	if len(b) < pointLen {
+		return nil, errors.New("confidential: ciphertext too short")
+	}
+	x, y := elliptic.Unmarshal(curve, b[:pointLen])
+	if x == nil {
+		return nil, errors.New("confidential: invalid ephemeral key")
+	}
+	aead, err := sealKey(x, y, priv.D)
+	if err != nil {
+		return nil, err
+	}
+	rest := b[pointLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("confidential: ciphertext too short")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("confidential: cannot decrypt: %v", err)
+	}
+	var o Opening
+	if err := json.Unmarshal(plain, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func sealKey(x, y *big.Int, d *big.Int) (cipher.AEAD, error) {
+	sx, _ := curve.ScalarMult(x, y, d.Bytes())
+	shared := make([]byte, 32)
+	b := sx.Bytes()
+	copy(shared[32-len(b):], b)
+	key := sha256.Sum256(shared)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ParsePublicKey decodes a PEM encoded P-256 public key.
+func ParsePublicKey(pemKey string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("confidential: public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != curve {
+		return nil, errors.New("confidential: public key is not a P-256 key")
+	}
+	return pub, nil
+}
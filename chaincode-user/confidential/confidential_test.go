@@ -0,0 +1,190 @@
+package confidential_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/confidential"
+	"github.com/stretchr/testify/require"
+)
+
+func newBlind(t *testing.T) *big.Int {
+	r, err := confidential.RandomScalar(rand.Reader)
+	require.NoError(t, err)
+	return r
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func proveRange(t *testing.T, v uint64, r *big.Int) *confidential.RangeProof {
+	proof, err := confidential.ProveRange(v, r, rand.Reader)
+	require.NoError(t, err)
+	return proof
+}
+
+func TestRangeProof(t *testing.T) {
+	for _, v := range []uint64{0, 1, 1500, 1<<confidential.Bits - 1} {
+		t.Run(fmt.Sprint(v), func(t *testing.T) {
+			r := newBlind(t)
+			proof := proveRange(t, v, r)
+			require.NoError(t, proof.Verify(confidential.Commit(v, r)))
+
+			encoded, err := proof.Marshal()
+			require.NoError(t, err)
+			parsed, err := confidential.ParseRangeProof(encoded)
+			require.NoError(t, err)
+			require.NoError(t, parsed.Verify(confidential.Commit(v, r)))
+		})
+	}
+
+	_, err := confidential.ProveRange(1<<confidential.Bits, newBlind(t), rand.Reader)
+	require.EqualError(t, err, "confidential: 4294967296 is out of range")
+}
+
+func TestRangeProofSoundness(t *testing.T) {
+	r := newBlind(t)
+	commitment := confidential.Commit(5, r)
+	proof := proveRange(t, 5, r)
+
+	for _, tc := range []struct {
+		name       string
+		commitment *confidential.Point
+		err        string
+	}{
+		{"other value", confidential.Commit(6, r), "confidential: bit 0 of the range proof is invalid"},
+		{"other blinding factor", confidential.Commit(5, newBlind(t)), "confidential: bit 0 of the range proof is invalid"},
+		// 5 - 6 is the commitment to -1, a huge value modulo the group order
+		{"negative remainder", commitment.Sub(confidential.CommitPublic(6)), "confidential: bit 0 of the range proof is invalid"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, proof.Verify(tc.commitment), tc.err)
+		})
+	}
+}
+
+func TestRangeProofTampered(t *testing.T) {
+	r := newBlind(t)
+	commitment := confidential.Commit(5, r)
+	encoded, err := proveRange(t, 5, r).Marshal()
+	require.NoError(t, err)
+	other, err := proveRange(t, 7, r).Marshal()
+	require.NoError(t, err)
+
+	tamper := func(modify func(bits []map[string]string)) string {
+		var p struct {
+			Bits []map[string]string `json:"bits"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(encoded), &p))
+		modify(p.Bits)
+		b, err := json.Marshal(p)
+		require.NoError(t, err)
+		return string(b)
+	}
+	var otherProof struct {
+		Bits []map[string]string `json:"bits"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(other), &otherProof))
+
+	for _, tc := range []struct {
+		name  string
+		proof string
+		err   string
+	}{
+		{"challenge changed", tamper(func(bits []map[string]string) { bits[3]["e0"] = bits[3]["e1"] }), "confidential: bit 3 of the range proof is invalid"},
+		{"response changed", tamper(func(bits []map[string]string) { bits[3]["s1"] = bits[3]["s0"] }), "confidential: bit 3 of the range proof is invalid"},
+		{"bit from another proof", tamper(func(bits []map[string]string) { bits[1] = otherProof.Bits[1] }), "confidential: bit 1 of the range proof is invalid"},
+		{"bits swapped", tamper(func(bits []map[string]string) { bits[0], bits[2] = bits[2], bits[0] }), "confidential: bit 0 of the range proof is invalid"},
+		{"bit missing", tamper(func(bits []map[string]string) { bits[len(bits)-1] = nil }), "confidential: bit 31 of the range proof is invalid"},
+		{"truncated", `{"bits":[]}`, "confidential: range proof must have 32 bits"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proof, err := confidential.ParseRangeProof(tc.proof)
+			require.NoError(t, err)
+			require.EqualError(t, proof.Verify(commitment), tc.err)
+		})
+	}
+}
+
+func TestCommitHomomorphic(t *testing.T) {
+	r1, r2 := newBlind(t), newBlind(t)
+	sum := new(big.Int).Add(r1, r2)
+	require.True(t, confidential.Commit(3, r1).Add(confidential.Commit(4, r2)).Equal(confidential.Commit(7, sum)))
+	require.True(t, confidential.Commit(7, r1).Sub(confidential.CommitPublic(4)).Equal(confidential.Commit(3, r1)))
+}
+
+func TestSeal(t *testing.T) {
+	key := newKey(t)
+	r := newBlind(t)
+	opening := &confidential.Opening{Value: 1500, Blind: r.Text(16)}
+	commitment := confidential.Commit(1500, r)
+
+	sealed, err := confidential.Seal(&key.PublicKey, opening, rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, confidential.VerifySealed(&key.PublicKey, sealed, commitment))
+
+	opened, err := confidential.Open(key, sealed)
+	require.NoError(t, err)
+	require.Equal(t, opening, opened)
+	openedCommitment, err := opened.Commitment()
+	require.NoError(t, err)
+	require.True(t, openedCommitment.Equal(commitment))
+
+	_, err = confidential.Open(newKey(t), sealed)
+	require.Error(t, err)
+}
+
+func TestVerifySealedRejects(t *testing.T) {
+	key := newKey(t)
+	r := newBlind(t)
+	commitment := confidential.Commit(1500, r)
+	sealed, err := confidential.Seal(&key.PublicKey, &confidential.Opening{Value: 1500, Blind: r.Text(16)}, rand.Reader)
+	require.NoError(t, err)
+	lower, err := confidential.Seal(&key.PublicKey, &confidential.Opening{Value: 15, Blind: r.Text(16)}, rand.Reader)
+	require.NoError(t, err)
+	reblinded, err := confidential.Seal(&key.PublicKey, &confidential.Opening{Value: 1500, Blind: newBlind(t).Text(16)}, rand.Reader)
+	require.NoError(t, err)
+
+	swap := func(from string, field string) string {
+		var s, l map[string]string
+		require.NoError(t, json.Unmarshal([]byte(sealed), &s))
+		require.NoError(t, json.Unmarshal([]byte(from), &l))
+		s[field] = l[field]
+		b, err := json.Marshal(s)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		key        *ecdsa.PublicKey
+		sealed     string
+		commitment *confidential.Point
+	}{
+		{"other commitment", &key.PublicKey, sealed, confidential.Commit(15, r)},
+		{"other key", &newKey(t).PublicKey, sealed, commitment},
+		{"lower amount", &key.PublicKey, lower, commitment},
+		{"other blinding factor", &key.PublicKey, reblinded, commitment},
+		{"amount swapped", &key.PublicKey, swap(lower, "e2"), commitment},
+		{"blinding factor swapped", &key.PublicKey, swap(reblinded, "e3"), commitment},
+		{"response swapped", &key.PublicKey, swap(lower, "z1"), commitment},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := confidential.VerifySealed(tc.key, tc.sealed, tc.commitment)
+			require.EqualError(t, err, "confidential: sealed amount does not match the commitment")
+		})
+	}
+
+	_, err = confidential.Open(key, swap(lower, "box"))
+	require.EqualError(t, err, "confidential: sealed opening does not match its amount and blinding factor")
+	_, err = confidential.Open(key, swap(reblinded, "box"))
+	require.EqualError(t, err, "confidential: sealed opening does not match its amount and blinding factor")
+}
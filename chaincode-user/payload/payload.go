@@ -0,0 +1,155 @@
+// Package payload builds and parses the signed payment request payloads
+// that merchants show as QR codes at the point of sale.
+//
+// A payload is "CBDC1.<body>.<signature>", where body is the compact JSON
+// encoding of Payload and signature is an ASN.1 ECDSA signature over the
+// SHA-256 of body, both base64url encoded without padding.
+package payload
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const prefix = "CBDC1"
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// Payload is a payment request signed by a merchant.
+// Expiry is unix seconds, zero means the payload does not expire.
+type Payload struct {
+	MerchantID string `json:"m"`
+	Amount     int    `json:"a"`
+	Currency   string `json:"c"`
+	Nonce      string `json:"n"`
+	Reference  string `json:"r,omitempty"`
+	Expiry     int64  `json:"e,omitempty"`
+}
+
+func (p *Payload) validate() error {
+	if p.MerchantID == "" {
+		return errors.New("payload: merchant ID is required")
+	}
+	if p.Amount <= 0 {
+		return errors.New("payload: amount must be positive")
+	}
+	if p.Currency == "" {
+		return errors.New("payload: currency is required")
+	}
+	if p.Nonce == "" {
+		return errors.New("payload: nonce is required")
+	}
+	return nil
+}
+
+// Expired reports whether the payload has expired at now (unix seconds).
+func (p *Payload) Expired(now int64) bool {
+	return p.Expiry != 0 && now >= p.Expiry
+}
+
+// Build encodes p and signs it with the merchant's key.
+func Build(p *Payload, key *ecdsa.PrivateKey) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(body)
+	r, ss, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig, err := asn1.Marshal(ecdsaSignature{R: r, S: ss})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return prefix + "." + enc.EncodeToString(body) + "." + enc.EncodeToString(sig), nil
+}
+
+// Parse decodes a payload without checking its signature.
+func Parse(s string) (*Payload, error) {
+	p, _, _, err := split(s)
+	return p, err
+}
+
+// Verify decodes a payload and checks that it was signed with pub.
+func Verify(s string, pub *ecdsa.PublicKey) (*Payload, error) {
+	p, body, sig, err := split(s)
+	if err != nil {
+		return nil, err
+	}
+	var esig ecdsaSignature
+	if rest, err := asn1.Unmarshal(sig, &esig); err != nil || len(rest) != 0 {
+		return nil, errors.New("payload: malformed signature")
+	}
+	digest := sha256.Sum256(body)
+	if !ecdsa.Verify(pub, digest[:], esig.R, esig.S) {
+		return nil, errors.New("payload: invalid signature")
+	}
+	return p, nil
+}
+
+func split(s string) (*Payload, []byte, []byte, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] != prefix {
+		return nil, nil, nil, errors.New("payload: malformed payload")
+	}
+	enc := base64.RawURLEncoding
+	body, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("payload: malformed body: %v", err)
+	}
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("payload: malformed signature: %v", err)
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, nil, nil, fmt.Errorf("payload: malformed body: %v", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, nil, nil, err
+	}
+	return &p, body, sig, nil
+}
+
+// ParsePublicKey reads a PEM encoded PKIX ECDSA public key.
+func ParsePublicKey(pemKey string) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("payload: no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("payload: not an ECDSA public key")
+	}
+	return pub, nil
+}
+
+// MarshalPublicKey encodes pub as a PEM encoded PKIX public key.
+func MarshalPublicKey(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
@@ -0,0 +1,125 @@
+package payload_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func testPayload() *payload.Payload {
+	return &payload.Payload{
+		MerchantID: "merchant1",
+		Amount:     1500,
+		Currency:   "korea",
+		Nonce:      "n-1",
+		Reference:  "order-7",
+		Expiry:     1700000600,
+	}
+}
+
+func TestBuildVerify(t *testing.T) {
+	key := newKey(t)
+	qr, err := payload.Build(testPayload(), key)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(qr, "CBDC1."))
+
+	parsed, err := payload.Parse(qr)
+	require.NoError(t, err)
+	require.Equal(t, testPayload(), parsed)
+
+	verified, err := payload.Verify(qr, &key.PublicKey)
+	require.NoError(t, err)
+	require.Equal(t, testPayload(), verified)
+
+	_, err = payload.Verify(qr, &newKey(t).PublicKey)
+	require.EqualError(t, err, "payload: invalid signature")
+}
+
+func TestBuildInvalid(t *testing.T) {
+	key := newKey(t)
+	for _, tc := range []struct {
+		name   string
+		modify func(p *payload.Payload)
+		err    string
+	}{
+		{"no merchant", func(p *payload.Payload) { p.MerchantID = "" }, "payload: merchant ID is required"},
+		{"zero amount", func(p *payload.Payload) { p.Amount = 0 }, "payload: amount must be positive"},
+		{"no currency", func(p *payload.Payload) { p.Currency = "" }, "payload: currency is required"},
+		{"no nonce", func(p *payload.Payload) { p.Nonce = "" }, "payload: nonce is required"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := testPayload()
+			tc.modify(p)
+			_, err := payload.Build(p, key)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	key := newKey(t)
+	qr, err := payload.Build(testPayload(), key)
+	require.NoError(t, err)
+	parts := strings.Split(qr, ".")
+	enc := base64.RawURLEncoding
+
+	body, err := enc.DecodeString(parts[1])
+	require.NoError(t, err)
+	raised := strings.Replace(string(body), `"a":1500`, `"a":9500`, 1)
+	require.NotEqual(t, string(body), raised)
+
+	other, err := payload.Build(&payload.Payload{MerchantID: "merchant2", Amount: 1500, Currency: "korea", Nonce: "n-1"}, newKey(t))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name string
+		qr   string
+		err  string
+	}{
+		{"amount changed", parts[0] + "." + enc.EncodeToString([]byte(raised)) + "." + parts[2], "payload: invalid signature"},
+		{"signature swapped", parts[0] + "." + parts[1] + "." + strings.Split(other, ".")[2], "payload: invalid signature"},
+		{"signature truncated", parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-4], "payload: malformed signature"},
+		{"wrong prefix", "CBDC2." + parts[1] + "." + parts[2], "payload: malformed payload"},
+		{"missing signature", parts[0] + "." + parts[1], "payload: malformed payload"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := payload.Verify(tc.qr, &key.PublicKey)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+	p := testPayload()
+	require.False(t, p.Expired(p.Expiry-1))
+	require.True(t, p.Expired(p.Expiry))
+	require.True(t, p.Expired(p.Expiry+1))
+
+	p.Expiry = 0
+	require.False(t, p.Expired(1<<40))
+}
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	key := newKey(t)
+	pemKey, err := payload.MarshalPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pub, err := payload.ParsePublicKey(pemKey)
+	require.NoError(t, err)
+	require.Equal(t, 0, key.PublicKey.X.Cmp(pub.X))
+	require.Equal(t, 0, key.PublicKey.Y.Cmp(pub.Y))
+
+	_, err = payload.ParsePublicKey("not a key")
+	require.EqualError(t, err, "payload: no PEM block found")
+}
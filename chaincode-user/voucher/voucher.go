@@ -0,0 +1,153 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package voucher signs and verifies the offline CBDC vouchers of a purse.
+//
+// A voucher is issued by the user chaincode when a purse is loaded and names the purse,
+// a serial number unique within the purse, and an amount. It carries no value until the
+// purse's secure element endorses it to a payee. Each holder passes it on offline by
+// endorsing it to the public key of the next device, and the last holder endorses it to
+// a ledger account ID to redeem it. An endorsement signs the voucher together with all
+// endorsements before it, so a chain cannot be reordered or cut and reused.
+//
+// Keys are ECDSA P-256. Public keys are the hex encoded PKIX form and signatures the
+// hex encoded ASN.1 form.
+package voucher
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Voucher is an offline payment of Amount drawn on the purse PurseID.
+type Voucher struct {
+	PurseID      string         `json:"purseID"`
+	Serial       int            `json:"serial"`
+	Amount       int            `json:"amount"`
+	Endorsements []*Endorsement `json:"endorsements,omitempty"`
+}
+
+// Endorsement passes a voucher to To, the public key of a device or a ledger account ID.
+type Endorsement struct {
+	To        string `json:"to"`
+	Signature string `json:"signature"`
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// GenerateKey creates a key pair, as a secure element would.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// EncodePublicKey returns the form of a public key used in purses and endorsements.
+func EncodePublicKey(key *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(der), nil
+}
+
+// ParsePublicKey decodes a public key encoded by EncodePublicKey.
+func ParsePublicKey(encoded string) (*ecdsa.PublicKey, error) {
+	der, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("not a P-256 public key")
+	}
+	return ecKey, nil
+}
+
+// Holder returns who the voucher was last endorsed to, or "" before its first endorsement.
+func (v *Voucher) Holder() string {
+	if len(v.Endorsements) == 0 {
+		return ""
+	}
+	return v.Endorsements[len(v.Endorsements)-1].To
+}
+
+// Endorse signs the voucher over to to with the key of its current holder,
+// which for the first endorsement is the key of the purse.
+func (v *Voucher) Endorse(key *ecdsa.PrivateKey, to string) error {
+	if to == "" {
+		return errors.New("an endorsement needs a payee")
+	}
+	digest := v.digest(len(v.Endorsements), to)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+	if err != nil {
+		return err
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	if err != nil {
+		return err
+	}
+	v.Endorsements = append(v.Endorsements, &Endorsement{To: to, Signature: hex.EncodeToString(signature)})
+	return nil
+}
+
+// Verify checks the chain of endorsements from purseKey, the public key of the purse,
+// and returns the holder of the voucher. Only the last endorsement may be to something
+// other than a public key.
+func (v *Voucher) Verify(purseKey string) (string, error) {
+	if len(v.Endorsements) == 0 {
+		return "", errors.New("the voucher has not been endorsed by its purse")
+	}
+	signer := purseKey
+	for i, endorsement := range v.Endorsements {
+		key, err := ParsePublicKey(signer)
+		if err != nil {
+			return "", fmt.Errorf("endorsement %d: invalid signer key: %v", i, err)
+		}
+		signature, err := hex.DecodeString(endorsement.Signature)
+		if err != nil {
+			return "", fmt.Errorf("endorsement %d: %v", i, err)
+		}
+		var sig ecdsaSignature
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) > 0 || sig.R == nil || sig.S == nil {
+			return "", fmt.Errorf("endorsement %d: malformed signature", i)
+		}
+		if !ecdsa.Verify(key, v.digest(i, endorsement.To), sig.R, sig.S) {
+			return "", fmt.Errorf("endorsement %d: invalid signature", i)
+		}
+		signer = endorsement.To
+	}
+	return signer, nil
+}
+
+// digest is what the n-th endorsement, to to, signs.
+func (v *Voucher) digest(n int, to string) []byte {
+	h := sha256.New()
+	for _, field := range []string{v.PurseID, strconv.Itoa(v.Serial), strconv.Itoa(v.Amount)} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	for _, endorsement := range v.Endorsements[:n] {
+		h.Write([]byte(endorsement.To))
+		h.Write([]byte{0})
+		h.Write([]byte(endorsement.Signature))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(to))
+	return h.Sum(nil)
+}
@@ -0,0 +1,98 @@
+package voucher_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-user/voucher"
+	"github.com/stretchr/testify/require"
+)
+
+type device struct {
+	key     *ecdsa.PrivateKey
+	encoded string
+}
+
+func newDevice(t *testing.T) *device {
+	key, err := voucher.GenerateKey()
+	require.NoError(t, err)
+	encoded, err := voucher.EncodePublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return &device{key: key, encoded: encoded}
+}
+
+// pass endorses a copy of v from each device to the next, the last endorsement to to.
+func pass(t *testing.T, v voucher.Voucher, to string, devices ...*device) *voucher.Voucher {
+	v.Endorsements = append([]*voucher.Endorsement(nil), v.Endorsements...)
+	for i, d := range devices {
+		payee := to
+		if i+1 < len(devices) {
+			payee = devices[i+1].encoded
+		}
+		require.NoError(t, v.Endorse(d.key, payee))
+	}
+	return &v
+}
+
+func TestVerify(t *testing.T) {
+	purse, alice, bob := newDevice(t), newDevice(t), newDevice(t)
+	issued := voucher.Voucher{PurseID: "purse1", Serial: 3, Amount: 50}
+	redeemed := pass(t, issued, "User2", purse, alice, bob)
+	endorsements := redeemed.Endorsements
+
+	// cut returns the redeemed voucher with the endorsements at indexes.
+	cut := func(indexes ...int) *voucher.Voucher {
+		v := *redeemed
+		v.Endorsements = nil
+		for _, i := range indexes {
+			v.Endorsements = append(v.Endorsements, endorsements[i])
+		}
+		return &v
+	}
+	changed := func(modify func(v *voucher.Voucher)) *voucher.Voucher {
+		v := *redeemed
+		modify(&v)
+		return &v
+	}
+
+	for _, tc := range []struct {
+		name     string
+		voucher  *voucher.Voucher
+		purseKey string
+		holder   string
+		err      string
+	}{
+		{"direct payment", pass(t, issued, "User2", purse), purse.encoded, "User2", ""},
+		{"multi-hop chain", redeemed, purse.encoded, "User2", ""},
+		{"held by a device", pass(t, issued, alice.encoded, purse), purse.encoded, alice.encoded, ""},
+		{"not endorsed", &issued, purse.encoded, "", "the voucher has not been endorsed by its purse"},
+		{"reordered", cut(0, 2, 1), purse.encoded, "", "endorsement 1: invalid signature"},
+		{"first endorsement cut", cut(1, 2), purse.encoded, "", "endorsement 0: invalid signature"},
+		{"middle endorsement cut", cut(0, 2), purse.encoded, "", "endorsement 1: invalid signature"},
+		// 앞부분만 남긴 체인은 이전 보유자의 것이므로 계좌로는 상환할 수 없다
+		{"last endorsement cut", cut(0, 1), purse.encoded, bob.encoded, ""},
+		{"wrong purse key", redeemed, alice.encoded, "", "endorsement 0: invalid signature"},
+		{"amount changed", changed(func(v *voucher.Voucher) { v.Amount = 500 }), purse.encoded, "", "endorsement 0: invalid signature"},
+		{"serial changed", changed(func(v *voucher.Voucher) { v.Serial = 4 }), purse.encoded, "", "endorsement 0: invalid signature"},
+		{"account endorsed mid-chain", pass(t, issued, "User2", purse, &device{key: alice.key, encoded: "User1"}, bob), purse.encoded, "", "endorsement 1: invalid signer key: encoding/hex: invalid byte: U+0055 'U'"},
+		{"payee changed", changed(func(v *voucher.Voucher) {
+			v.Endorsements = append(append([]*voucher.Endorsement(nil), endorsements[:2]...), &voucher.Endorsement{To: "User3", Signature: endorsements[2].Signature})
+		}), purse.encoded, "", "endorsement 2: invalid signature"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			holder, err := tc.voucher.Verify(tc.purseKey)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.holder, holder)
+		})
+	}
+}
+
+func TestEndorseNeedsPayee(t *testing.T) {
+	v := voucher.Voucher{PurseID: "purse1", Serial: 1, Amount: 50}
+	require.EqualError(t, v.Endorse(newDevice(t).key, ""), "an endorsement needs a payee")
+	require.Empty(t, v.Endorsements)
+}
@@ -33,6 +33,7 @@ function clean {
     rm -Rf ./chaincode-go/vendor
     rm -Rf ./chaincode-user/vendor
     rm -Rf ./chaincode-regulatory/vendor
+    rm -Rf ./chaincode-asset/vendor
     rm -Rf ./atcc/vendor
 }
 
@@ -193,6 +194,16 @@ function packageChaincode() {
             --path /opt/gopath/src/github.com/asset-transfer-basic/chaincode-regulatory \
             --label regulatorychaincode_1.0
 
+    # reference asset chaincode for DvP on the regulatory channel
+    docker exec -i -t \
+        -w /opt/gopath/src/github.com/asset-transfer-basic/chaincode-asset \
+        cli go mod vendor
+
+    docker exec -i -t \
+        cli peer lifecycle chaincode package assetchaincode.tar.gz \
+            --path /opt/gopath/src/github.com/asset-transfer-basic/chaincode-asset \
+            --label assetchaincode_1.0
+
     echo "packaging ~~~"
 }
 
@@ -208,6 +219,16 @@ function allinstallChaincode() {
     installChaincode 0 centralbank regulatorychaincode
     installChaincode 0 commercialbank regulatorychaincode
     installChaincode 1 commercialbank regulatorychaincode
+    installChaincode 0 centralbank assetchaincode
+    installChaincode 0 commercialbank assetchaincode
+    installChaincode 1 commercialbank assetchaincode
+}
+
+# packageID prints the hash part of the package ID of an installed chaincode package,
+# the SHA-256 of the package file.
+function packageID() {
+    chaincodeName=${1:-assetchaincode}
+    docker exec cli sha256sum ${chaincodeName}.tar.gz | cut -d ' ' -f 1
 }
 
 function installChaincode() {
@@ -284,6 +305,14 @@ function allapproveForMyOrg() {
     sleep 1
     checkCommitReadiness centralbank regulatorychaincode regulatory-channel
     checkCommitReadiness commercialbank regulatorychaincode regulatory-channel
+
+    sleep 1
+    approveForMyOrg centralbank assetchaincode regulatory-channel $(packageID assetchaincode)
+    sleep 1
+    approveForMyOrg commercialbank assetchaincode regulatory-channel $(packageID assetchaincode)
+    sleep 1
+    checkCommitReadiness centralbank assetchaincode regulatory-channel
+    checkCommitReadiness commercialbank assetchaincode regulatory-channel
 }
 
 
@@ -302,6 +331,11 @@ function approveForMyOrg() {
     
     policy+=')'
 
+    collections=""
+    if [ "$channel" == "user-channel" ]; then
+        collections="--collections-config /opt/gopath/src/github.com/asset-transfer-basic/chaincode-user/collections_config.json"
+    fi
+
     TLS_PATH=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/${org}.islab.re.kr/peers/peer0.${org}.islab.re.kr/tls
     ORDERER_CA=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/ordererOrganizations/islab.re.kr/orderers/orderer0.islab.re.kr/msp/tlscacerts/tlsca.islab.re.kr-cert.pem
     # sample chaincode
@@ -321,7 +355,7 @@ function approveForMyOrg() {
             --version 1.0 \
             --package-id ${chaincodeName}_1.0:${packid} \
             --sequence 1 \
-            --signature-policy ${policy}
+            --signature-policy ${policy} $collections
 
     # my chaincode
     # docker exec -i -t \
@@ -362,6 +396,11 @@ function checkCommitReadiness() {
     
     policy+=')'
 
+    collections=""
+    if [ "$channel" == "user-channel" ]; then
+        collections="--collections-config /opt/gopath/src/github.com/asset-transfer-basic/chaincode-user/collections_config.json"
+    fi
+
     TLS_PATH=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/${org}.islab.re.kr/peers/peer0.${org}.islab.re.kr/tls
     ORDERER_CA=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/ordererOrganizations/islab.re.kr/orderers/orderer0.islab.re.kr/msp/tlscacerts/tlsca.islab.re.kr-cert.pem
     docker exec -i -t \
@@ -379,7 +418,7 @@ function checkCommitReadiness() {
         --name ${chaincodeName} \
         --version 1.0 \
         --sequence 1 \
-        --signature-policy $policy
+        --signature-policy $policy $collections
         # "OR('centralbankOrg.peer','commercialbankOrg.peer','consumerOrg.peer')"
 }
 
@@ -397,6 +436,11 @@ function commitChaincodeDefinition() {
 
     policy+=')'
 
+    collections=""
+    if [ "$channel" == "user-channel" ]; then
+        collections="--collections-config /opt/gopath/src/github.com/asset-transfer-basic/chaincode-user/collections_config.json"
+    fi
+
     TLS_PATH=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/${org}.islab.re.kr/peers/peer0.${org}.islab.re.kr/tls
     ORDERER_CA=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/ordererOrganizations/islab.re.kr/orderers/orderer0.islab.re.kr/msp/tlscacerts/tlsca.islab.re.kr-cert.pem
     PEER_0_COMMERCIALBANK_TLS_CA_CERT=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/commercialbank.islab.re.kr/peers/peer0.commercialbank.islab.re.kr/tls/ca.crt
@@ -432,7 +476,7 @@ function commitChaincodeDefinition() {
             --peerAddresses peer0.centralbank.islab.re.kr:7051 \
             --tlsRootCertFiles $PEER_0_CENTRALBANK_TLS_CA \
             --sequence 1 \
-            --signature-policy $policy
+            --signature-policy $policy $collections
 }
 
 function commitChaincodeDefinitionTestR() {
@@ -449,6 +493,11 @@ function commitChaincodeDefinitionTestR() {
 
     policy+=')'
 
+    collections=""
+    if [ "$channel" == "user-channel" ]; then
+        collections="--collections-config /opt/gopath/src/github.com/asset-transfer-basic/chaincode-user/collections_config.json"
+    fi
+
     TLS_PATH=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/${org}.islab.re.kr/peers/peer0.${org}.islab.re.kr/tls
     ORDERER_CA=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/ordererOrganizations/islab.re.kr/orderers/orderer0.islab.re.kr/msp/tlscacerts/tlsca.islab.re.kr-cert.pem
     PEER_0_COMMERCIALBANK_TLS_CA_CERT=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/commercialbank.islab.re.kr/peers/peer0.commercialbank.islab.re.kr/tls/ca.crt
@@ -478,7 +527,7 @@ function commitChaincodeDefinitionTestR() {
             --peerAddresses peer0.centralbank.islab.re.kr:7051 \
             --tlsRootCertFiles $PEER_0_CENTRALBANK_TLS_CA \
             --sequence 1 \
-            --signature-policy $policy
+            --signature-policy $policy $collections
 }
 
 function commitChaincodeDefinitionTest() {
@@ -495,6 +544,11 @@ function commitChaincodeDefinitionTest() {
 
     policy+=')'
 
+    collections=""
+    if [ "$channel" == "user-channel" ]; then
+        collections="--collections-config /opt/gopath/src/github.com/asset-transfer-basic/chaincode-user/collections_config.json"
+    fi
+
     TLS_PATH=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/peerOrganizations/${org}.islab.re.kr/peers/peer0.${org}.islab.re.kr/tls
     ORDERER_CA=/opt/gopath/src/github.com/hyperledger/fabric/peer/organizations/ordererOrganizations/islab.re.kr/orderers/orderer0.islab.re.kr/msp/tlscacerts/tlsca.islab.re.kr-cert.pem
     PEER_0_CENTRALBANK_TLS_CA=$TLS_PATH/ca.crt
@@ -515,7 +569,7 @@ function commitChaincodeDefinitionTest() {
             --peerAddresses peer0.centralbank.islab.re.kr:7051 \
             --tlsRootCertFiles $PEER_0_CENTRALBANK_TLS_CA \
             --sequence 1 \
-            --signature-policy $policy
+            --signature-policy $policy $collections
 }
 
 function queryCommitted() {
@@ -910,11 +964,13 @@ function chaincode_install {
     # commitChaincodeDefinition centralbank
     # commitChaincodeDefinitionTest centralbank mychaincode centralbank-channel
     # commitChaincodeDefinitionTestR centralbank regulatorychaincode regulatory-channel
+    # commitChaincodeDefinitionTestR centralbank assetchaincode regulatory-channel
     # queryCommitted centralbank
     # queryCommitted consumer
     # queryCommitted centralbank mychaincode centralbank-channel
     # queryCommitted centralbank regulatorychaincode regulatory-channel
     # queryCommitted commercialbank regulatorychaincode regulatory-channel
+    # queryCommitted centralbank assetchaincode regulatory-channel
 
     # chaincodeInvokeInit centralbank mychaincode centralbank-channel
     # chaincodeInvokeInit commercialbank regulatorychaincode regulatory-channel